@@ -0,0 +1,71 @@
+package hostsfile
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultFileMode is used when writing a hosts file that does not exist yet
+const defaultFileMode fs.FileMode = 0644
+
+// writeFileAtomic writes the output of write to a temp file next to path, syncs it to disk, copies over the mode and
+// owner of the existing file and renames it over path. Readers will only ever see the old or the new contents.
+func writeFileAtomic(path string, write func(io.Writer) error) (err error) {
+	// follow symlinks so we replace the target and not the link itself
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	mode := defaultFileMode
+	info, statErr := os.Stat(path)
+	switch {
+	case statErr == nil:
+		mode = info.Mode().Perm()
+	case !errors.Is(statErr, fs.ErrNotExist):
+		return statErr
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// cleanup the temp file if anything goes wrong before the rename
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if info != nil {
+		if err = chownLike(tmp, info); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
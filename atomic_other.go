@@ -0,0 +1,12 @@
+//go:build !unix
+// +build !unix
+
+package hostsfile
+
+import (
+	"io/fs"
+	"os"
+)
+
+func chownLike(f *os.File, info fs.FileInfo) error { return nil }
+func syncDir(dir string) error                     { return nil }
@@ -0,0 +1,85 @@
+package hostsfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "hosts")
+	assert.Nil(t, os.WriteFile(fp, []byte("127.0.0.1 old\n"), 0600))
+
+	assert.Nil(t, writeFileAtomic(fp, func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 new\n")
+		return err
+	}))
+
+	data, err := os.ReadFile(fp)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 new\n", string(data))
+
+	info, err := os.Stat(fp)
+	assert.Nil(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// a failed write leaves the original alone and no temp files behind
+	assert.Error(t, writeFileAtomic(fp, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("boom")
+	}))
+	data, err = os.ReadFile(fp)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 new\n", string(data))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteFileAtomic_NewFile(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "hosts")
+	assert.Nil(t, writeFileAtomic(fp, func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 localhost\n")
+		return err
+	}))
+
+	info, err := os.Stat(fp)
+	assert.Nil(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, defaultFileMode, info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomic_Symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on windows")
+	}
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "hosts.real")
+	link := filepath.Join(dir, "hosts")
+	assert.Nil(t, os.WriteFile(target, []byte(""), 0644))
+	assert.Nil(t, os.Symlink(target, link))
+
+	hosts, err := NewCustomHosts(link)
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("127.0.0.1", "localhost"))
+	assert.Nil(t, hosts.Flush())
+
+	fi, err := os.Lstat(link)
+	assert.Nil(t, err)
+	assert.True(t, fi.Mode()&os.ModeSymlink != 0)
+
+	data, err := os.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 localhost"+eol, string(data))
+}
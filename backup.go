@@ -0,0 +1,185 @@
+package hostsfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is used in backup file names, it sorts the same alphabetically and chronologically
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// BackupInfo describes a backup written by a BackupManager
+type BackupInfo struct {
+	Path string    // Path to the backup file
+	Time time.Time // When the backup was taken
+	Size int64     // Size of the backup in bytes
+}
+
+// BackupManager writes timestamped copies of a hosts file on disk to a directory and prunes them based on its retention policy
+type BackupManager struct {
+	Path     string        // Path to the hosts file being backed up
+	Dir      string        // Directory the backups are written to, defaults to the directory of Path
+	MaxCount int           // Keep at most this many backups, 0 or less keeps everything
+	MaxAge   time.Duration // Remove backups older than this, 0 or less keeps everything
+
+	now     func() time.Time
+	storage Storage // of the Hosts the manager was made for, nil is disk
+}
+
+// NewBackupManager returns a BackupManager for the hosts file at path writing backups into dir, with no retention limits
+func NewBackupManager(path, dir string) *BackupManager {
+	return &BackupManager{
+		Path: path,
+		Dir:  dir,
+		now:  time.Now,
+	}
+}
+
+// BackupManager returns a BackupManager for the hosts file writing backups into dir
+func (h *Hosts) BackupManager(dir string) *BackupManager {
+	b := NewBackupManager(h.Path, dir)
+	b.storage = h.Storage()
+	return b
+}
+
+// Create copies the current hosts file to a new timestamped backup and prunes old backups
+func (b *BackupManager) Create() (BackupInfo, error) {
+	if err := b.checkStorage(); err != nil {
+		return BackupInfo{}, err
+	}
+	source, err := os.Open(b.Path)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(b.dir(), 0755); err != nil {
+		return BackupInfo{}, err
+	}
+
+	taken := b.clock().UTC()
+	path := filepath.Join(b.dir(), fmt.Sprintf("%s.%s.bak", filepath.Base(b.Path), taken.Format(backupTimeFormat)))
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, source)
+		return err
+	}); err != nil {
+		return BackupInfo{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+
+	if _, err := b.Prune(); err != nil {
+		return BackupInfo{}, err
+	}
+
+	return BackupInfo{Path: path, Time: taken, Size: info.Size()}, nil
+}
+
+// List returns the existing backups, newest first
+func (b *BackupManager) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(b.dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(b.Path) + "."
+	var backups []BackupInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+
+		taken, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak"))
+		if err != nil {
+			continue // not one of ours
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, BackupInfo{
+			Path: filepath.Join(b.dir(), name),
+			Time: taken,
+			Size: info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// Prune removes the backups falling outside of MaxCount and MaxAge and returns what was removed
+func (b *BackupManager) Prune() ([]BackupInfo, error) {
+	backups, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []BackupInfo
+	now := b.clock()
+	for i, backup := range backups {
+		if (b.MaxCount > 0 && i >= b.MaxCount) || (b.MaxAge > 0 && now.Sub(backup.Time) > b.MaxAge) {
+			if err := os.Remove(backup.Path); err != nil {
+				return removed, err
+			}
+			removed = append(removed, backup)
+		}
+	}
+	return removed, nil
+}
+
+// Restore atomically replaces the hosts file with the contents of backup, Load needs to be called on any Hosts using
+// the file to pick up the restored contents
+func (b *BackupManager) Restore(backup BackupInfo) error {
+	if err := b.checkStorage(); err != nil {
+		return err
+	}
+	source, err := os.Open(backup.Path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	return writeFileAtomic(b.Path, func(w io.Writer) error {
+		_, err := io.Copy(w, source)
+		return err
+	})
+}
+
+// checkStorage returns ErrBackupUnsupported when the hosts file isn't on disk
+func (b *BackupManager) checkStorage() error {
+	if b.storage != nil && !isDiskStorage(b.storage) {
+		return ErrBackupUnsupported
+	}
+	return nil
+}
+
+func (b *BackupManager) dir() string {
+	if b.Dir == "" {
+		return filepath.Dir(b.Path)
+	}
+	return b.Dir
+}
+
+func (b *BackupManager) clock() time.Time {
+	if b.now == nil {
+		return time.Now()
+	}
+	return b.now()
+}
@@ -0,0 +1,95 @@
+package hostsfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupManager(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n")
+	dir := filepath.Join(t.TempDir(), "backups")
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manager := hosts.BackupManager(dir)
+	manager.MaxCount = 3
+	manager.now = func() time.Time { return now }
+
+	backups, err := manager.List()
+	assert.Nil(t, err)
+	assert.Len(t, backups, 0)
+
+	first, err := manager.Create()
+	assert.Nil(t, err)
+	assert.Equal(t, now, first.Time)
+	assert.Equal(t, int64(len("127.0.0.1 localhost\n")), first.Size)
+	assert.Equal(t, filepath.Join(dir, "hosts.20240101T000000.000000000Z.bak"), first.Path)
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Hour)
+		_, err := manager.Create()
+		assert.Nil(t, err)
+	}
+
+	// only the newest 3 are kept
+	backups, err = manager.List()
+	assert.Nil(t, err)
+	assert.Len(t, backups, 3)
+	assert.Equal(t, now, backups[0].Time)
+	assert.Equal(t, now.Add(-2*time.Hour), backups[2].Time)
+
+	// age based retention
+	manager.MaxAge = 90 * time.Minute
+	removed, err := manager.Prune()
+	assert.Nil(t, err)
+	assert.Len(t, removed, 1)
+	backups, err = manager.List()
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+
+	// files that aren't backups are left alone
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "hosts.notes.bak"), nil, 0644))
+	backups, err = manager.List()
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+}
+
+func TestHosts_AutoBackupAndRestore(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n")
+	hosts.AutoBackup = hosts.BackupManager(t.TempDir())
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "bad"))
+	assert.Nil(t, hosts.Flush())
+
+	backups, err := hosts.AutoBackup.List()
+	assert.Nil(t, err)
+	assert.Len(t, backups, 1)
+
+	assert.Nil(t, hosts.Restore(backups[0]))
+	assert.False(t, hosts.HasHostname("bad"))
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n", string(data))
+}
+
+func TestBackupManager_UnsupportedStorage(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"/nonexistent-dir/hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := NewHostsWithStorage("/nonexistent-dir/hosts", storage)
+	assert.Nil(t, err)
+
+	manager := hosts.BackupManager(t.TempDir())
+	_, err = manager.Create()
+	assert.ErrorIs(t, err, ErrBackupUnsupported)
+
+	// backups aren't silently skipped
+	hosts.AutoBackup = NewBackupManager(hosts.Path, t.TempDir())
+	assert.Nil(t, hosts.Add("10.0.0.1", "app"))
+	assert.ErrorIs(t, hosts.Flush(), ErrBackupUnsupported)
+	assert.Equal(t, "127.0.0.1 localhost\n", readStorage(t, storage, hosts.Path))
+
+	assert.ErrorIs(t, hosts.Restore(BackupInfo{Path: filepath.Join(t.TempDir(), "hosts.bak")}), ErrBackupUnsupported)
+	assert.True(t, hosts.Has("10.0.0.1", "app"), "nothing is reloaded")
+}
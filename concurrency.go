@@ -0,0 +1,41 @@
+package hostsfile
+
+import "sync"
+
+// WithConcurrencySafe makes all the methods of Hosts safe to call from multiple goroutines. Reads like Has and String
+// run concurrently while edits, Load and Flush hold an exclusive lock. Lines must not be used directly while other
+// goroutines are using the Hosts, and hooks added with Subscribe are called with the lock held so they can't call
+// back into the Hosts. Each method is atomic on its own, transactions aren't isolated from other goroutines, see Tx.
+func WithConcurrencySafe() Option {
+	return func(h *Hosts) {
+		if h.mu == nil {
+			h.mu = new(sync.RWMutex)
+		}
+	}
+}
+
+func unlocked() {}
+
+// linesCopy returns the path and a copy of the lines under the read lock, so they can be used while others edit
+func (h *Hosts) linesCopy() (string, []HostsLine) {
+	defer h.lockRead()()
+	return h.Path, copyLines(h.Lines)
+}
+
+// lockWrite takes the exclusive lock when the Hosts is concurrency safe and returns the func releasing it
+func (h *Hosts) lockWrite() func() {
+	if h.mu == nil {
+		return unlocked
+	}
+	h.mu.Lock()
+	return h.mu.Unlock
+}
+
+// lockRead takes the shared lock when the Hosts is concurrency safe and returns the func releasing it
+func (h *Hosts) lockRead() func() {
+	if h.mu == nil {
+		return unlocked
+	}
+	h.mu.RLock()
+	return h.mu.RUnlock
+}
@@ -0,0 +1,109 @@
+package hostsfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// run with -race to check the locking
+func TestHosts_ConcurrencySafe(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithConcurrencySafe(), WithHistory(5),
+		WithConflictMode(ConflictReapply), WithWatchInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := hosts.Watch(ctx)
+	assert.Nil(t, err)
+	go func() {
+		for range events {
+		}
+	}()
+	hosts.Subscribe(func(Event) error { return nil })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ip := fmt.Sprintf("10.0.%d.%d", i, j)
+				host := fmt.Sprintf("host-%d-%d", i, j)
+				assert.Nil(t, hosts.Add(ip, host))
+				assert.Nil(t, hosts.Section("s").Add(ip, "s"+host))
+				assert.Nil(t, hosts.Disable(ip, host))
+				assert.Nil(t, hosts.Enable(ip, host))
+				hosts.Clean()
+				hosts.Undo()
+				hosts.Redo()
+				tx := hosts.Begin()
+				tx.SortIPs()
+				assert.Nil(t, tx.Rollback())
+				if j%5 == 0 {
+					// an undo past a reload is replayed as replacing every line, which conflicts with the other writer
+					if err := hosts.Flush(); err != nil {
+						assert.ErrorIs(t, err, ErrConcurrentModification)
+					}
+				}
+				assert.Nil(t, hosts.Remove(ip, host))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				hosts.Has("10.0.0.1", "host-0-1")
+				hosts.HasAll("127.0.0.1", "localhost")
+				hosts.HasHostname("localhost")
+				_ = hosts.String()
+				_, _ = hosts.WriteTo(io.Discard)
+				_ = hosts.Sections()
+				_ = hosts.Section("s").Lines()
+				_ = hosts.FindByMetadata("owner", "")
+				_ = hosts.DisabledEntries()
+				_, _ = hosts.Plan()
+				_ = Diff(hosts, hosts)
+				_, _ = hosts.HasBeenModified()
+			}
+		}()
+	}
+
+	// something else editing the file at the same time
+	for j := 0; j < 10; j++ {
+		assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "127.0.0.1 localhost\n10.1.0.%d external\n", j)
+			return err
+		}))
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	// undo and redo interleave between goroutines so only check the result is consistent
+	assert.True(t, hosts.Has("127.0.0.1", "localhost"))
+	assert.Equal(t, hosts.String(), loadHosts(t, strings.TrimSuffix(hosts.String(), eol)).String())
+}
+
+func TestHosts_ConcurrencySafeTransactionNotIsolated(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost")
+	WithConcurrencySafe()(hosts)
+
+	tx := hosts.Begin()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Nil(t, hosts.Add("10.0.0.9", "other"))
+	}()
+	<-done
+	assert.True(t, tx.HasHostname("other"), "the transaction sees edits from other goroutines")
+
+	// and rolls them back with its own, see Tx
+	assert.Nil(t, tx.Rollback())
+	assert.False(t, hosts.HasHostname("other"))
+}
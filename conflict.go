@@ -0,0 +1,97 @@
+package hostsfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrConcurrentModification is returned by Flush when the hosts file was changed by someone else after it was loaded
+var ErrConcurrentModification = errors.New("hosts file was modified since it was loaded")
+
+// ConflictMode controls what Flush does when the hosts file was changed on disk since it was loaded
+type ConflictMode int
+
+const (
+	// ConflictFail makes Flush return ErrConcurrentModification without writing anything
+	ConflictFail ConflictMode = iota
+	// ConflictReapply makes Flush reload the hosts file and replay the edits made since Load on top of it
+	ConflictReapply
+	// ConflictMerge makes Flush do a three way merge (see Merge3) of the lines as they were at Load, the lines in memory
+	// and the file on disk. Merge conflicts are returned as a *MergeError without writing anything.
+	ConflictMerge
+)
+
+// checksumFile returns the sha256 of the contents of the file at path in storage
+func checksumFile(storage Storage, path string) ([]byte, error) {
+	f, err := storage.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return nil, err
+	}
+	return sum.Sum(nil), nil
+}
+
+// checkConflict is called before writing, if the file changed on disk since Load it's handled based on h.Conflict
+func (h *Hosts) checkConflict() error {
+	if h.checksum == nil {
+		return nil // never loaded from disk, nothing to compare against
+	}
+
+	// only the contents matter here, a touched file with the same contents is safe to overwrite
+	changed, err := h.contentChanged()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // nothing there to clobber
+	}
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	switch h.Conflict {
+	case ConflictReapply:
+		return h.reapply()
+	case ConflictMerge:
+		return h.merge()
+	default:
+		return ErrConcurrentModification
+	}
+}
+
+// contentChanged compares the file on disk to the checksum taken during Load
+func (h *Hosts) contentChanged() (bool, error) {
+	sum, err := checksumFile(h.Storage(), h.Path)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(sum, h.checksum), nil
+}
+
+// merge replaces Lines with a three way merge of the lines at Load, Lines and the file on disk
+func (h *Hosts) merge() error {
+	theirs, err := NewHostsWithStorage(h.Path, h.storage)
+	if err != nil {
+		return err
+	}
+
+	merged, conflicts := merge3(newHostsFromLines(h.Path, h.base), h, theirs)
+	if len(conflicts) > 0 {
+		return &MergeError{Conflicts: conflicts}
+	}
+
+	h.Lines = merged.Lines
+	h.reindex()
+	h.publish()
+	// the merge is now based on what's on disk
+	h.modTime, h.checksum, h.base = theirs.modTime, theirs.checksum, theirs.base
+	return nil
+}
@@ -0,0 +1,104 @@
+package hostsfile
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_HasBeenModified(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n")
+
+	modified, err := hosts.HasBeenModified()
+	assert.Nil(t, err)
+	assert.False(t, modified)
+
+	// same size and mtime, only the hash can catch it
+	info, err := os.Stat(hosts.Path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte("127.0.0.2 localhost\n"), 0644))
+	assert.Nil(t, os.Chtimes(hosts.Path, info.ModTime(), info.ModTime()))
+
+	modified, err = hosts.HasBeenModified()
+	assert.Nil(t, err)
+	assert.True(t, modified)
+}
+
+func TestHosts_FlushConcurrentModification(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n")
+	assert.Nil(t, hosts.Add("10.0.0.1", "ours"))
+
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte("127.0.0.1 localhost\n10.0.0.2 theirs\n"), 0644))
+	assert.ErrorIs(t, hosts.Flush(), ErrConcurrentModification)
+
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n10.0.0.2 theirs\n", string(data))
+
+	// touching the file without changing it is not a conflict
+	assert.Nil(t, hosts.Load())
+	assert.Nil(t, hosts.Add("10.0.0.1", "ours"))
+	later := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(hosts.Path, later, later))
+	assert.Nil(t, hosts.Flush())
+}
+
+func TestHosts_FlushConflictReapply(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n10.0.0.9 old\n")
+	hosts.Conflict = ConflictReapply
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "ours"))
+	assert.Nil(t, hosts.RemoveByHostname("old"))
+
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte("127.0.0.1 localhost\n10.0.0.9 old\n10.0.0.2 theirs\n"), 0644))
+	assert.Nil(t, hosts.Flush())
+
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 localhost"+eol+"10.0.0.2 theirs"+eol+"10.0.0.1 ours"+eol, string(data))
+	assert.Len(t, hosts.pending, 0)
+}
+
+func TestHosts_PendingEdits(t *testing.T) {
+	hosts := newHosts()
+	assert.Nil(t, hosts.Add("127.0.0.1", "host1"))
+	assert.Error(t, hosts.Add("bad", "host1"))
+	hosts.Clean()
+	assert.Len(t, hosts.pending, 2)
+	assert.Equal(t, opAdd, hosts.pending[0].op)
+	assert.Equal(t, opClean, hosts.pending[1].op)
+}
+
+func TestHosts_ReapplyLineEdits(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n10.0.0.1 app\n")
+	hosts.Conflict = ConflictReapply
+
+	// the line moved, replaying by position would overwrite theirs
+	assert.Nil(t, hosts.ReplaceLine(1, "10.0.0.2 app"))
+	theirs := "127.0.0.1 localhost\n192.168.1.5 nas\n10.0.0.1 app\n"
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte(theirs), 0644))
+	assert.ErrorIs(t, hosts.Flush(), ErrConcurrentModification)
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, theirs, string(data))
+
+	// the line is unchanged so it's replayed
+	assert.Nil(t, hosts.Load())
+	assert.Nil(t, hosts.ReplaceLine(2, "10.0.0.2 app"))
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte(theirs+"10.0.0.3 db\n"), 0644))
+	assert.Nil(t, hosts.Flush())
+	assert.True(t, hosts.Has("10.0.0.2", "app"))
+	assert.True(t, hosts.Has("10.0.0.3", "db"))
+	assert.True(t, hosts.Has("192.168.1.5", "nas"))
+
+	// undoing across a Flush sets all the lines, that can't be replayed on a changed file
+	hosts, err = New(WithPath(hosts.Path), WithHistory(5), WithHistoryAcrossFlush(true), WithConflictMode(ConflictReapply))
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("10.0.0.4", "new"))
+	assert.Nil(t, hosts.Flush())
+	assert.True(t, hosts.Undo())
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte(theirs), 0644))
+	assert.ErrorIs(t, hosts.Flush(), ErrConcurrentModification)
+}
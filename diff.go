@@ -0,0 +1,443 @@
+package hostsfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of change between two Hosts found by Diff
+type ChangeKind string
+
+const (
+	ChangeAdded          ChangeKind = "added"           // a host was mapped to an ip
+	ChangeRemoved        ChangeKind = "removed"         // a host was no longer mapped to an ip
+	ChangeIPChanged      ChangeKind = "ip_changed"      // a host was mapped to a different ip
+	ChangeCommentChanged ChangeKind = "comment_changed" // the trailing comment of an entry line changed
+	ChangeMoved          ChangeKind = "moved"           // an entry line moved to a different position
+)
+
+// Change is a single entry level change between two Hosts, line numbers start at 1 and are 0 when they don't apply
+type Change struct {
+	Kind       ChangeKind `json:"kind"`
+	IP         string     `json:"ip"`                    // ip in the new hosts, or the old one for removed
+	OldIP      string     `json:"old_ip,omitempty"`      // ip in the old hosts for ip_changed
+	Hosts      []string   `json:"hosts"`                 // the host for mapping changes, all the hosts of the line otherwise
+	Comment    string     `json:"comment,omitempty"`     // trailing comment in the new hosts for comment_changed
+	OldComment string     `json:"old_comment,omitempty"` // trailing comment in the old hosts for comment_changed
+	Line       int        `json:"line,omitempty"`        // line in the new hosts
+	OldLine    int        `json:"old_line,omitempty"`    // line in the old hosts
+}
+
+// String to make Change a fmt.Stringer
+func (c Change) String() string {
+	hosts := strings.Join(c.Hosts, " ")
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s %s", c.IP, hosts)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s %s", c.IP, hosts)
+	case ChangeIPChanged:
+		return fmt.Sprintf("~ %s %s -> %s", hosts, c.OldIP, c.IP)
+	case ChangeCommentChanged:
+		return fmt.Sprintf("~ %s %s comment %q -> %q", c.IP, hosts, c.OldComment, c.Comment)
+	case ChangeMoved:
+		return fmt.Sprintf("~ %s %s moved line %d -> %d", c.IP, hosts, c.OldLine, c.Line)
+	}
+	return string(c.Kind)
+}
+
+// HostsDiff is the difference between two Hosts returned by Diff
+type HostsDiff struct {
+	Changes []Change
+
+	fromPath, toPath string
+	from, to         []string // raw lines
+}
+
+// Diff compares the entries of a and b and returns what changed going from a to b. Host mappings are compared by
+// hostname and ip so reordering or reformatting lines only shows up as moved lines, comment and blank lines are only
+// part of the text diff, see HostsDiff.Unified.
+func Diff(a, b *Hosts) *HostsDiff {
+	aPath, aLines := a.linesCopy()
+	bPath, bLines := b.linesCopy()
+	return diff(aPath, bPath, aLines, bLines)
+}
+
+func diff(fromPath, toPath string, a, b []HostsLine) *HostsDiff {
+	d := &HostsDiff{
+		fromPath: fromPath,
+		toPath:   toPath,
+		from:     rawLines(a),
+		to:       rawLines(b),
+	}
+	d.Changes = append(d.Changes, mappingChanges(a, b)...)
+	d.Changes = append(d.Changes, lineChanges(a, b)...)
+	return d
+}
+
+// HasChanges returns true if anything changed in the file, including comment and blank lines
+func (d *HostsDiff) HasChanges() bool {
+	return len(d.Changes) > 0 || !equalStrings(d.from, d.to)
+}
+
+// String returns the unified diff with 3 lines of context
+func (d *HostsDiff) String() string {
+	return d.Unified(3)
+}
+
+// Unified renders the text diff of the files in the unified format with context lines around each change, it's empty
+// when the files are the same
+func (d *HostsDiff) Unified(context int) string {
+	if context < 0 {
+		context = 0
+	}
+	ops := diffLines(d.from, d.to)
+
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change and the context before it
+		for start < len(ops) && ops[start].kind == diffEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+
+		// extend the hunk while the changes are close enough to share context
+		end, equal := start, 0
+		for end < len(ops) && equal <= 2*context {
+			if ops[end].kind == diffEqual {
+				equal++
+			} else {
+				equal = 0
+			}
+			end++
+		}
+		if equal > context {
+			end -= equal - context // only keep context lines after the last change
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", diffLabel(d.fromPath, "a"), diffLabel(d.toPath, "b"))
+		}
+		writeHunk(&b, ops[first:end], d.from, d.to)
+		start = end
+	}
+	return b.String()
+}
+
+func diffLabel(path, fallback string) string {
+	if path == "" {
+		return fallback
+	}
+	return path
+}
+
+func writeHunk(b *strings.Builder, ops []diffOp, from, to []string) {
+	fromStart, toStart := ops[0].a, ops[0].b
+	fromCount, toCount := 0, 0
+	for _, op := range ops {
+		if op.kind != diffInsert {
+			fromCount++
+		}
+		if op.kind != diffDelete {
+			toCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(fromStart, fromCount), hunkRange(toStart, toCount))
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			b.WriteString(" " + from[op.a] + "\n")
+		case diffDelete:
+			b.WriteString("-" + from[op.a] + "\n")
+		case diffInsert:
+			b.WriteString("+" + to[op.b] + "\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk range, an empty range starts at the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func rawLines(lines []HostsLine) []string {
+	raw := make([]string, len(lines))
+	for i := range lines {
+		raw[i] = lines[i].ToRaw()
+	}
+	return raw
+}
+
+// mapping is a host mapped to an ip on a line
+type mapping struct {
+	ip   string
+	line int
+}
+
+func hostMappings(lines []HostsLine) map[string][]mapping {
+	mappings := make(map[string][]mapping)
+	for pos, line := range lines {
+		if !isEntryLine(line) {
+			continue
+		}
+		for _, host := range line.Hosts {
+			mappings[host] = append(mappings[host], mapping{ip: line.IP, line: pos + 1})
+		}
+	}
+	return mappings
+}
+
+// mappingChanges returns the added, removed and changed host to ip mappings, ordered by host
+func mappingChanges(a, b []HostsLine) []Change {
+	from, to := hostMappings(a), hostMappings(b)
+	hosts := make([]string, 0, len(from)+len(to))
+	for host := range from {
+		hosts = append(hosts, host)
+	}
+	for host := range to {
+		if _, ok := from[host]; !ok {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	var changes []Change
+	for _, host := range hosts {
+		removed := missingMappings(from[host], to[host])
+		added := missingMappings(to[host], from[host])
+		if len(removed) == 1 && len(added) == 1 {
+			changes = append(changes, Change{Kind: ChangeIPChanged, IP: added[0].ip, OldIP: removed[0].ip,
+				Hosts: []string{host}, Line: added[0].line, OldLine: removed[0].line})
+			continue
+		}
+		for _, m := range removed {
+			changes = append(changes, Change{Kind: ChangeRemoved, IP: m.ip, Hosts: []string{host}, OldLine: m.line})
+		}
+		for _, m := range added {
+			changes = append(changes, Change{Kind: ChangeAdded, IP: m.ip, Hosts: []string{host}, Line: m.line})
+		}
+	}
+	return changes
+}
+
+// missingMappings returns the mappings in a for ips that aren't in b
+func missingMappings(a, b []mapping) []mapping {
+	var missing []mapping
+	for _, m := range a {
+		if !containsMappingIP(b, m.ip) && !containsMappingIP(missing, m.ip) {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
+
+func containsMappingIP(mappings []mapping, ip string) bool {
+	for _, m := range mappings {
+		if m.ip == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// entryKey identifies an entry line by its ip and hosts, ignoring the layout and the comment
+func entryKey(line HostsLine) string {
+	return line.IP + " " + strings.Join(line.Hosts, " ")
+}
+
+// lineChanges returns the comment changes and moved lines between the entry lines of a and b, ordered by line in b
+func lineChanges(a, b []HostsLine) []Change {
+	var fromPos, toPos []int
+	var fromKeys, toKeys []string
+	for pos, line := range a {
+		if isEntryLine(line) {
+			fromPos, fromKeys = append(fromPos, pos), append(fromKeys, entryKey(line))
+		}
+	}
+	for pos, line := range b {
+		if isEntryLine(line) {
+			toPos, toKeys = append(toPos, pos), append(toKeys, entryKey(line))
+		}
+	}
+
+	// pair up the entry lines, lines kept in order are matched by the diff and the rest by their key are moved
+	pairs := make(map[int]int) // position in b to position in a
+	moved := make(map[int]bool)
+	unmatched := make(map[string][]int)
+	var inserted []int
+	for _, op := range diffLines(fromKeys, toKeys) {
+		switch op.kind {
+		case diffEqual:
+			pairs[toPos[op.b]] = fromPos[op.a]
+		case diffDelete:
+			unmatched[fromKeys[op.a]] = append(unmatched[fromKeys[op.a]], fromPos[op.a])
+		case diffInsert:
+			inserted = append(inserted, op.b)
+		}
+	}
+	for _, i := range inserted {
+		if positions := unmatched[toKeys[i]]; len(positions) > 0 {
+			pairs[toPos[i]] = positions[0]
+			moved[toPos[i]] = true
+			unmatched[toKeys[i]] = positions[1:]
+		}
+	}
+
+	// lines that had hosts changed are paired by ip when it's the only line for the ip on both sides
+	fromByIP, toByIP := make(map[string][]int), make(map[string][]int)
+	for _, pos := range fromPos {
+		fromByIP[a[pos].IP] = append(fromByIP[a[pos].IP], pos)
+	}
+	for _, pos := range toPos {
+		toByIP[b[pos].IP] = append(toByIP[b[pos].IP], pos)
+	}
+	for ip, positions := range toByIP {
+		if _, ok := pairs[positions[0]]; !ok && len(positions) == 1 && len(fromByIP[ip]) == 1 {
+			pairs[positions[0]] = fromByIP[ip][0]
+		}
+	}
+
+	var changes []Change
+	for _, pos := range toPos {
+		old, ok := pairs[pos]
+		if !ok {
+			continue
+		}
+		line, oldLine := b[pos], a[old]
+		if line.Comment != oldLine.Comment {
+			changes = append(changes, Change{Kind: ChangeCommentChanged, IP: line.IP, Hosts: line.Hosts,
+				Comment: line.Comment, OldComment: oldLine.Comment, Line: pos + 1, OldLine: old + 1})
+		}
+		if moved[pos] {
+			changes = append(changes, Change{Kind: ChangeMoved, IP: line.IP, Hosts: line.Hosts, Line: pos + 1, OldLine: old + 1})
+		}
+	}
+	return changes
+}
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp is one step of turning a into b, a and b are the positions in each
+type diffOp struct {
+	kind diffKind
+	a, b int
+}
+
+// diffLines returns the shortest edit script turning a into b using the Myers algorithm, the common start and end are
+// skipped first as most changes to a hosts file are small
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: diffEqual, a: i, b: i})
+	}
+	for _, op := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		op.a += prefix
+		op.b += prefix
+		ops = append(ops, op)
+	}
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, diffOp{kind: diffEqual, a: len(a) - i, b: len(b) - i})
+	}
+	return ops
+}
+
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	// v[k] is the furthest x reached on diagonal k, trace keeps v from the start of each round to walk back through
+	max := n + m
+	v := make([]int, 2*max+2)
+	var trace [][]int
+	var rounds int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[max-d:max+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1] // down, insert from b
+			} else {
+				x = v[max+k-1] + 1 // right, delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				rounds = d
+				break search
+			}
+		}
+	}
+
+	// walk back from the end collecting the ops in reverse
+	var ops []diffOp
+	x, y := n, m
+	for d := rounds; d > 0; d-- {
+		prev := trace[d] // v at the start of round d, indexed from diagonal -d
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: diffEqual, a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: diffInsert, a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: diffDelete, a: x, b: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{kind: diffEqual, a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
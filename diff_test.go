@@ -0,0 +1,137 @@
+package hostsfile
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	a := loadHosts(t,
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1 app api # staging",
+		"10.0.0.2 db",
+		"10.0.0.3 cache",
+		"10.0.0.4 old",
+	)
+	b := loadHosts(t,
+		"# header",
+		"10.0.0.3 cache",
+		"127.0.0.1 localhost",
+		"10.0.0.1 app # production",
+		"10.0.0.2 db",
+		"10.0.0.5 api new",
+	)
+
+	d := Diff(a, b)
+	assert.True(t, d.HasChanges())
+	assert.Equal(t, []Change{
+		{Kind: ChangeIPChanged, IP: "10.0.0.5", OldIP: "10.0.0.1", Hosts: []string{"api"}, Line: 6, OldLine: 3},
+		{Kind: ChangeAdded, IP: "10.0.0.5", Hosts: []string{"new"}, Line: 6},
+		{Kind: ChangeRemoved, IP: "10.0.0.4", Hosts: []string{"old"}, OldLine: 6},
+		{Kind: ChangeMoved, IP: "10.0.0.3", Hosts: []string{"cache"}, Line: 2, OldLine: 5},
+		{Kind: ChangeCommentChanged, IP: "10.0.0.1", Hosts: []string{"app"}, Comment: " production", OldComment: " staging", Line: 4, OldLine: 3},
+	}, d.Changes)
+	assert.Equal(t, "~ api 10.0.0.1 -> 10.0.0.5", d.Changes[0].String())
+	assert.Equal(t, "+ 10.0.0.5 new", d.Changes[1].String())
+	assert.Equal(t, "~ 10.0.0.3 cache moved line 5 -> 2", d.Changes[3].String())
+
+	assert.Equal(t, strings.Join([]string{
+		"--- a",
+		"+++ b",
+		"@@ -1,6 +1,6 @@",
+		" # header",
+		"+10.0.0.3 cache",
+		" 127.0.0.1 localhost",
+		"-10.0.0.1 app api # staging",
+		"+10.0.0.1 app # production",
+		" 10.0.0.2 db",
+		"-10.0.0.3 cache",
+		"-10.0.0.4 old",
+		"+10.0.0.5 api new",
+		"",
+	}, "\n"), d.String())
+
+	same := Diff(a, a)
+	assert.False(t, same.HasChanges())
+	assert.Empty(t, same.Changes)
+	assert.Empty(t, same.String())
+}
+
+func TestDiff_Reformatted(t *testing.T) {
+	a := loadHosts(t, "127.0.0.1 localhost", "10.0.0.1 app")
+	b := loadHosts(t, "127.0.0.1\tlocalhost", "10.0.0.1 app", "# added comment")
+
+	d := Diff(a, b)
+	assert.Empty(t, d.Changes)
+	assert.True(t, d.HasChanges())
+}
+
+func TestHostsDiff_Unified(t *testing.T) {
+	var from, to []string
+	for i := 0; i < 20; i++ {
+		from = append(from, "line"+string(rune('a'+i)))
+	}
+	to = append(to, from...)
+	to[1] = "changed"
+	to = append(to[:15], to[16:]...)
+	d := &HostsDiff{fromPath: "/etc/hosts", toPath: "/etc/hosts.new", from: from, to: to}
+
+	assert.Equal(t, strings.Join([]string{
+		"--- /etc/hosts",
+		"+++ /etc/hosts.new",
+		"@@ -1,3 +1,3 @@",
+		" linea",
+		"-lineb",
+		"+changed",
+		" linec",
+		"@@ -15,3 +15,2 @@",
+		" lineo",
+		"-linep",
+		" lineq",
+		"",
+	}, "\n"), d.Unified(1))
+
+	d = &HostsDiff{from: nil, to: []string{"one"}}
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+one\n", d.Unified(3))
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d"}
+	for i := 0; i < 200; i++ {
+		a := make([]string, r.Intn(12))
+		for j := range a {
+			a[j] = words[r.Intn(len(words))]
+		}
+		b := make([]string, r.Intn(12))
+		for j := range b {
+			b[j] = words[r.Intn(len(words))]
+		}
+
+		// replaying the ops has to give back both sides
+		var gotA, gotB []string
+		for _, op := range diffLines(a, b) {
+			if op.kind != diffInsert {
+				gotA = append(gotA, a[op.a])
+			}
+			if op.kind != diffDelete {
+				gotB = append(gotB, b[op.b])
+			}
+			if op.kind == diffEqual {
+				assert.Equal(t, a[op.a], b[op.b])
+			}
+		}
+		assert.Equal(t, len(a), len(gotA))
+		assert.Equal(t, len(b), len(gotB))
+		if len(a) > 0 {
+			assert.Equal(t, a, gotA)
+		}
+		if len(b) > 0 {
+			assert.Equal(t, b, gotB)
+		}
+	}
+}
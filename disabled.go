@@ -0,0 +1,124 @@
+package hostsfile
+
+// Disable comments out the lines for ip so they stay in the file but aren't used, e.g. "# 127.0.0.1 host". When hosts
+// are passed only those hosts are disabled, they're moved to a disabled line right after the line they came from.
+func (h *Hosts) Disable(ip string, hosts ...string) error {
+	return h.apply(edit{op: opDisable, ip: ip, hosts: hosts})
+}
+
+// Enable uncomments the disabled lines for ip. When hosts are passed only those hosts are enabled, they're moved to an
+// enabled line right before the disabled line they came from.
+func (h *Hosts) Enable(ip string, hosts ...string) error {
+	return h.apply(edit{op: opEnable, ip: ip, hosts: hosts})
+}
+
+// IsDisabled returns true if the ip/host combo exists as a disabled entry
+func (h *Hosts) IsDisabled(ip, host string) bool {
+	defer h.lockRead()()
+	return len(h.disabledLines(ip, host)) > 0
+}
+
+// DisabledEntries returns all the disabled entry lines
+func (h *Hosts) DisabledEntries() []HostsLine {
+	defer h.lockRead()()
+	var lines []HostsLine
+	for _, pos := range h.disabledLines("", "") {
+		lines = append(lines, h.Lines[pos])
+	}
+	return lines
+}
+
+// WithIncludeDisabled makes Has, HasIP, HasHostname and the lookups built on them also match disabled entries
+func WithIncludeDisabled(include bool) Option {
+	return func(h *Hosts) {
+		h.config.includeDisabled = include
+	}
+}
+
+// disabledLines returns the positions of the disabled lines matching ip and host, an empty ip or host matches any
+func (h *Hosts) disabledLines(ip, host string) []int {
+	return disabledLines(h.Lines, ip, host)
+}
+
+func disabledLines(lines []HostsLine, ip, host string) []int {
+	var positions []int
+	for pos, line := range lines {
+		if !line.Disabled || (ip != "" && line.IP != ip) || (host != "" && !itemInSliceString(host, line.Hosts)) {
+			continue
+		}
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+func (h *Hosts) disable(ip string, hosts ...string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	h.toggle(ip, hosts, false)
+	return nil
+}
+
+func (h *Hosts) enable(ip string, hosts ...string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	h.toggle(ip, hosts, true)
+	return nil
+}
+
+// toggle enables or disables the lines for ip, splitting lines where only some of their hosts are toggled
+func (h *Hosts) toggle(ip string, hosts []string, enable bool) {
+	lines := make([]HostsLine, len(h.Lines))
+	copy(lines, h.Lines)
+	h.clear()
+
+	for _, line := range lines {
+		// only toggle entries for ip that are in the opposite state
+		if line.IP != ip || line.Disabled != enable || (!line.Disabled && line.IsComment()) {
+			h.addLine(line)
+			continue
+		}
+
+		var toggled, kept []string
+		for _, host := range line.Hosts {
+			if len(hosts) == 0 || itemInSliceString(host, hosts) {
+				toggled = append(toggled, host)
+			} else {
+				kept = append(kept, host)
+			}
+		}
+
+		switch {
+		case len(toggled) == 0:
+			h.addLine(line)
+		case len(kept) == 0:
+			toggleLine(&line, enable)
+			h.addLine(line)
+		default:
+			moved := line // keeps the layout of the line it came from
+			moved.Hosts = toggled
+			moved.RegenRaw()
+			toggleLine(&moved, enable)
+			line.Hosts = kept
+			line.RegenRaw()
+
+			// enabled hosts go before the disabled line, disabled hosts after the enabled line
+			if enable {
+				h.addLine(moved)
+				h.addLine(line)
+			} else {
+				h.addLine(line)
+				h.addLine(moved)
+			}
+		}
+	}
+}
+
+func toggleLine(line *HostsLine, enable bool) {
+	if enable {
+		line.enable()
+	} else {
+		line.disable()
+	}
+}
@@ -0,0 +1,142 @@
+package hostsfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHostsLine_Disabled(t *testing.T) {
+	hl := NewHostsLine("# 10.0.0.1\tapp api # staging @owner=ci")
+	assert.True(t, hl.Disabled)
+	assert.True(t, hl.IsComment())
+	assert.False(t, hl.IsValid())
+	assert.Equal(t, "10.0.0.1", hl.IP)
+	assert.Equal(t, []string{"app", "api"}, hl.Hosts)
+	assert.Equal(t, " 10.0.0.1\tapp api # staging @owner=ci", hl.Comment, "the same as any other comment line")
+	assert.Equal(t, " staging @owner=ci", hl.entryComment())
+	assert.Equal(t, map[string]string{"owner": "ci"}, hl.Metadata)
+	assert.Equal(t, "# 10.0.0.1\tapp api # staging @owner=ci", hl.ToRaw())
+
+	hl.Hosts = []string{"app"}
+	assert.Equal(t, "# 10.0.0.1\tapp # staging @owner=ci", hl.ToRaw())
+	assert.Nil(t, hl.SetMetadata("owner", "bob"))
+	assert.Equal(t, "# 10.0.0.1\tapp # staging @owner=bob", hl.Raw)
+	assert.Equal(t, " 10.0.0.1\tapp # staging @owner=bob", hl.Comment)
+
+	assert.True(t, NewHostsLine("#10.0.0.1 app").Disabled)
+	assert.True(t, NewHostsLine("  # 10.0.0.1 app").Disabled)
+
+	for _, raw := range []string{
+		"# This is a comment", "# 10.0.0.1", "# localhost 127.0.0.1", "# 10.0.0.1 is the gateway!",
+		"#  10.0.0.1 app", "#\t10.0.0.1 app", "#      102.54.94.97     rhino.acme.com          # source server",
+	} {
+		hl := NewHostsLine(raw)
+		assert.False(t, hl.Disabled, raw)
+		assert.False(t, hl.IsValid(), raw)
+		assert.Empty(t, hl.IP, raw)
+		assert.Equal(t, raw[1:], hl.Comment, raw)
+		assert.Equal(t, raw, hl.ToRaw())
+	}
+}
+
+func TestHosts_DisabledWindowsDefault(t *testing.T) {
+	hosts, err := NewHostsFromReader(strings.NewReader(ProfileWindows.DefaultContent))
+	assert.Nil(t, err)
+
+	// the examples in the comments aren't disabled entries
+	assert.Empty(t, hosts.DisabledEntries())
+	assert.Nil(t, hosts.Enable("102.54.94.97"))
+	assert.Nil(t, hosts.Enable("127.0.0.1"))
+	assert.Equal(t, ProfileWindows.DefaultContent, strings.TrimSuffix(hosts.String(), eol))
+	for _, line := range hosts.Lines {
+		assert.False(t, line.IsValid(), line.Raw)
+	}
+}
+
+func TestHosts_Disable(t *testing.T) {
+	hosts := loadHosts(t,
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1  app api # staging",
+	)
+
+	assert.Nil(t, hosts.Disable("10.0.0.1", "api"))
+	assert.Equal(t, strings.Join([]string{
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1  app # staging",
+		"# 10.0.0.1  api # staging",
+	}, eol)+eol, hosts.String())
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.False(t, hosts.Has("10.0.0.1", "api"))
+	assert.False(t, hosts.HasHostname("api"))
+	assert.True(t, hosts.IsDisabled("10.0.0.1", "api"))
+
+	assert.Nil(t, hosts.Disable("10.0.0.1"))
+	assert.False(t, hosts.HasIP("10.0.0.1"))
+	assert.Len(t, hosts.DisabledEntries(), 2)
+	assert.Equal(t, "# 10.0.0.1  app # staging", hosts.Lines[2].Raw)
+
+	assert.NotNil(t, hosts.Disable("not an ip"))
+}
+
+func TestHosts_Enable(t *testing.T) {
+	hosts := loadHosts(t,
+		"127.0.0.1 localhost",
+		"#10.0.0.1 app api",
+		"# just a comment",
+	)
+	assert.False(t, hosts.Has("10.0.0.1", "app"))
+
+	assert.Nil(t, hosts.Enable("10.0.0.1", "app"))
+	assert.Equal(t, strings.Join([]string{
+		"127.0.0.1 localhost",
+		"10.0.0.1 app",
+		"#10.0.0.1 api",
+		"# just a comment",
+	}, eol)+eol, hosts.String())
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.True(t, hosts.IsDisabled("10.0.0.1", "api"))
+
+	assert.Nil(t, hosts.Enable("10.0.0.1"))
+	assert.True(t, hosts.HasAll("10.0.0.1", "app", "api"))
+	assert.Empty(t, hosts.DisabledEntries())
+	assert.Equal(t, "10.0.0.1 api", hosts.Lines[2].Raw)
+}
+
+func TestHosts_IncludeDisabled(t *testing.T) {
+	hosts, err := New(WithStorage(NewMemoryStorage(map[string][]byte{
+		"hosts": []byte("127.0.0.1 localhost\n# 10.0.0.1 app\n"),
+	})), WithPath("hosts"), WithIncludeDisabled(true))
+	assert.Nil(t, err)
+
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.True(t, hosts.HasIP("10.0.0.1"))
+	assert.True(t, hosts.HasHostname("app"))
+	assert.True(t, hosts.HasAny("10.0.0.1", "app", "api"))
+	assert.False(t, hosts.Has("10.0.0.1", "api"))
+}
+
+func TestHosts_DisabledIgnoredByEdits(t *testing.T) {
+	hosts := loadHosts(t,
+		"# 10.0.0.1 old",
+		"# 10.0.0.3 zeta alpha",
+		"10.0.0.2 b",
+		"10.0.0.1 a",
+		"10.0.0.1 c",
+	)
+
+	hosts.SortHosts()
+	assert.Equal(t, "# 10.0.0.3 zeta alpha", hosts.Lines[1].Raw)
+
+	hosts.Clean()
+	assert.Equal(t, "# 10.0.0.1 old", hosts.Lines[0].Raw)
+	assert.Equal(t, "# 10.0.0.3 zeta alpha", hosts.Lines[1].Raw)
+	assert.Equal(t, []string{"zeta", "alpha"}, hosts.Lines[1].Hosts)
+	assert.Equal(t, []string{"a", "c"}, hosts.Lines[2].Hosts)
+
+	assert.Nil(t, hosts.Remove("10.0.0.1"))
+	assert.True(t, hosts.IsDisabled("10.0.0.1", "old"))
+}
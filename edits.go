@@ -0,0 +1,174 @@
+package hostsfile
+
+import (
+	"fmt"
+	"time"
+)
+
+// editOp names one of the public methods that change the contents of Hosts
+type editOp string
+
+const (
+	opAdd                  editOp = "add"
+	opAddRaw               editOp = "add_raw"
+	opRemove               editOp = "remove"
+	opRemoveByHostname     editOp = "remove_by_hostname"
+	opRemoveByIP           editOp = "remove_by_ip"
+	opClear                editOp = "clear"
+	opClean                editOp = "clean"
+	opCombineDuplicateIPs  editOp = "combine_duplicate_ips"
+	opRemoveDuplicateHosts editOp = "remove_duplicate_hosts"
+	opSortHosts            editOp = "sort_hosts"
+	opSortIPs              editOp = "sort_ips"
+	opHostsPerLine         editOp = "hosts_per_line"
+	opSectionAdd           editOp = "section_add"
+	opSectionRemove        editOp = "section_remove"
+	opSectionClear         editOp = "section_clear"
+	opFormat               editOp = "format"
+	opDisable              editOp = "disable"
+	opEnable               editOp = "enable"
+	opSetMetadata          editOp = "set_metadata"
+	opDeleteMetadata       editOp = "delete_metadata"
+	opAddUntil             editOp = "add_until"
+	opPruneExpired         editOp = "prune_expired"
+	opReplaceLine          editOp = "replace_line"
+	opSetLines             editOp = "set_lines"
+)
+
+// edit is a single change made through the public api. Edits made since the last Load are kept so they can be
+// replayed on top of a newer copy of the hosts file, see ConflictReapply.
+type edit struct {
+	op      editOp
+	section string // name of the section for section edits
+	ip      string
+	hosts   []string  // hostnames, or raw lines for add_raw, replace_line and set_lines
+	count   int       // hosts per line
+	line    int       // position of the line for replace_line
+	old     *string   // raw contents of the line replace_line replaced, replaying fails when it changed
+	key     string    // metadata key
+	value   string    // metadata value
+	time    time.Time // expiry for add_until, the time pruned at for prune_expired
+	style   FormatStyle
+}
+
+// apply makes the change described by e and records it as pending when it succeeds
+func (h *Hosts) apply(e edit) error {
+	defer h.lockWrite()()
+	return h.applyEdit(e)
+}
+
+// applyQuiet is apply for the methods without an error return, the error is kept for Err instead
+func (h *Hosts) applyQuiet(e edit) {
+	defer h.lockWrite()()
+	h.lastErr = h.applyEdit(e)
+}
+
+// Err returns why the last edit made by a method without an error return, such as Clean or RemoveByIP, wasn't made,
+// usually a hook vetoing it. It's nil when the edit was made.
+func (h *Hosts) Err() error {
+	defer h.lockRead()()
+	return h.lastErr
+}
+
+// applyEdit is apply for callers already holding the lock
+func (h *Hosts) applyEdit(e edit) error {
+	defer h.publish()
+	e.hosts = append([]string(nil), e.hosts...) // don't hold on to the caller's slice
+
+	record := h.config.historyDepth > 0 && !h.replaying
+	notify := len(h.hooks) > 0 && !h.replaying
+	before := h.state() // an edit failing part way through is put back so it's all or nothing
+
+	var err error
+	switch e.op {
+	case opAdd:
+		err = h.add(e.ip, e.hosts...)
+	case opAddRaw:
+		err = h.addRaw(e.hosts...)
+	case opRemove:
+		err = h.remove(e.ip, e.hosts...)
+	case opRemoveByHostname:
+		err = h.removeByHostname(e.hosts[0])
+	case opRemoveByIP:
+		h.removeByIP(e.ip)
+	case opClear:
+		h.clear()
+	case opClean:
+		h.clean()
+	case opCombineDuplicateIPs:
+		h.combineDuplicateIPs()
+	case opRemoveDuplicateHosts:
+		h.removeDuplicateHosts()
+	case opSortHosts:
+		h.sortHosts()
+	case opSortIPs:
+		h.sortIPs()
+	case opHostsPerLine:
+		h.hostsPerLine(e.count)
+	case opSectionAdd:
+		err = h.Section(e.section).add(e.ip, e.hosts...)
+	case opSectionRemove:
+		err = h.Section(e.section).remove(e.ip, e.hosts...)
+	case opSectionClear:
+		err = h.Section(e.section).clear()
+	case opFormat:
+		h.format(e.style)
+	case opDisable:
+		err = h.disable(e.ip, e.hosts...)
+	case opEnable:
+		err = h.enable(e.ip, e.hosts...)
+	case opSetMetadata:
+		err = h.setMetadata(e.ip, e.key, e.value)
+	case opDeleteMetadata:
+		err = h.deleteMetadata(e.ip, e.key)
+	case opAddUntil:
+		err = h.addUntil(e.ip, e.time, e.hosts...)
+	case opPruneExpired:
+		h.pruneExpired(e.time)
+	case opReplaceLine:
+		e.old, err = h.replaceLine(e.line, e.hosts[0], e.old)
+	case opSetLines:
+		if h.replaying {
+			// every line is replaced so replaying it would drop all the changes made to the file by others
+			err = fmt.Errorf("replacing all the lines of a changed file: %w", ErrConcurrentModification)
+			break
+		}
+		h.setLines(e.hosts)
+	default:
+		err = fmt.Errorf("unknown edit %q", e.op)
+	}
+	if err != nil {
+		h.restore(before)
+		return err
+	}
+
+	if notify {
+		if err := h.emit(changeEvents(e.op, before.lines, h.Lines)...); err != nil {
+			h.restore(before) // vetoed
+			return err
+		}
+	}
+
+	h.pending = append(h.pending, e)
+	if record {
+		h.history.push(before, h.generation, h.config.historyDepth)
+	}
+	return nil
+}
+
+// reapply reloads the hosts file from disk and replays the pending edits on top of it
+func (h *Hosts) reapply() error {
+	pending := h.pending
+	if err := h.reload(); err != nil {
+		return err
+	}
+
+	h.replaying = true
+	defer func() { h.replaying = false }()
+	for _, e := range pending {
+		if err := h.applyEdit(e); err != nil {
+			return fmt.Errorf("reapplying %s: %w", e.op, err)
+		}
+	}
+	return nil
+}
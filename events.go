@@ -0,0 +1,147 @@
+package hostsfile
+
+// EventType is the kind of change an Event reports
+type EventType string
+
+const (
+	EventEntryAdded   EventType = "entry_added"   // a host was mapped to an ip
+	EventEntryRemoved EventType = "entry_removed" // a host was no longer mapped to an ip
+	EventLineChanged  EventType = "line_changed"  // a line was added, removed or changed
+	EventReloaded     EventType = "reloaded"      // the hosts file was loaded again with Load
+	EventFlushed      EventType = "flushed"       // the hosts file was written with Flush
+)
+
+// Event is sent to the hooks added with Subscribe
+type Event struct {
+	Type EventType
+	Op   string    // the edit that caused the event e.g. "add", "undo" or "rollback", "load" and "flush" for reloaded and flushed
+	IP   string    // ip of the entry for entry events
+	Host string    // hostname of the entry for entry events
+	Line int       // position in Lines, the old position when the line or entry was removed
+	Old  HostsLine // the line before a line change, zero when the line was added
+	New  HostsLine // the line after a line change, zero when the line was removed
+}
+
+// Hook receives events, returning an error from an entry or line event vetoes the edit: it's undone and the error is
+// returned from the method that made it. Errors from reloaded and flushed events are returned from Load and Flush but
+// the file has already been read or written. Undo, Redo and Rollback can't be vetoed, errors from their events are
+// ignored.
+type Hook func(Event) error
+
+type subscription struct {
+	id   int
+	hook Hook
+}
+
+// Subscribe adds hook to be called for every change and returns a func to remove it again
+func (h *Hosts) Subscribe(hook Hook) (unsubscribe func()) {
+	defer h.lockWrite()()
+	h.nextHookID++
+	id := h.nextHookID
+	h.hooks = append(h.hooks, subscription{id: id, hook: hook})
+	return func() {
+		defer h.lockWrite()()
+		for i, s := range h.hooks {
+			if s.id == id {
+				h.hooks = append(h.hooks[:i:i], h.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit sends events to the hooks in order, stopping at the first error
+func (h *Hosts) emit(events ...Event) error {
+	for _, e := range events {
+		for _, s := range h.hooks {
+			if err := s.hook(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreWithEvents is restore for Undo, Redo and Rollback, which tell the hooks what they put back
+func (h *Hosts) restoreWithEvents(op editOp, s hostsState) {
+	before := h.Lines
+	h.restore(s)
+	if len(h.hooks) == 0 {
+		return
+	}
+	for _, e := range changeEvents(op, before, h.Lines) {
+		for _, s := range h.hooks {
+			_ = s.hook(e) // what was put back can't be vetoed
+		}
+	}
+}
+
+// changeEvents returns the entry events followed by the line events for going from the lines before to after
+func changeEvents(op editOp, before, after []HostsLine) []Event {
+	var events []Event
+	oldEntries, newEntries := entrySet(before), entrySet(after)
+	seen := make(map[entry]bool)
+	for pos, line := range before {
+		if !isEntryLine(line) {
+			continue
+		}
+		for _, host := range line.Hosts {
+			if e := (entry{ip: line.IP, host: host}); !newEntries[e] && !seen[e] {
+				seen[e] = true
+				events = append(events, Event{Type: EventEntryRemoved, Op: string(op), IP: e.ip, Host: e.host, Line: pos})
+			}
+		}
+	}
+	for pos, line := range after {
+		if !isEntryLine(line) {
+			continue
+		}
+		for _, host := range line.Hosts {
+			if e := (entry{ip: line.IP, host: host}); !oldEntries[e] && !seen[e] {
+				seen[e] = true
+				events = append(events, Event{Type: EventEntryAdded, Op: string(op), IP: e.ip, Host: e.host, Line: pos})
+			}
+		}
+	}
+
+	// pair up the removed and added lines of each change as line changes
+	var deleted, inserted []diffOp
+	flush := func() {
+		for i := 0; i < len(deleted) || i < len(inserted); i++ {
+			event := Event{Type: EventLineChanged, Op: string(op)}
+			if i < len(deleted) {
+				event.Old, event.Line = before[deleted[i].a], deleted[i].a
+			}
+			if i < len(inserted) {
+				event.New, event.Line = after[inserted[i].b], inserted[i].b
+			}
+			events = append(events, event)
+		}
+		deleted, inserted = nil, nil
+	}
+	for _, o := range diffLines(rawLines(before), rawLines(after)) {
+		switch o.kind {
+		case diffDelete:
+			deleted = append(deleted, o)
+		case diffInsert:
+			inserted = append(inserted, o)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return events
+}
+
+func entrySet(lines []HostsLine) map[entry]bool {
+	set := make(map[entry]bool)
+	for _, line := range lines {
+		if !isEntryLine(line) {
+			continue
+		}
+		for _, host := range line.Hosts {
+			set[entry{ip: line.IP, host: host}] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,127 @@
+package hostsfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_Subscribe(t *testing.T) {
+	hosts, _ := newHistoryHosts(t)
+	var events []Event
+	unsubscribe := hosts.Subscribe(func(e Event) error {
+		events = append(events, e)
+		return nil
+	})
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "api"))
+	assert.Equal(t, []Event{
+		{Type: EventEntryAdded, Op: "add", IP: "10.0.0.1", Host: "api", Line: 1},
+		{Type: EventLineChanged, Op: "add", Line: 1, Old: NewHostsLine("10.0.0.1 app"), New: hosts.Lines[1]},
+	}, events)
+
+	events = nil
+	assert.Nil(t, hosts.AddRaw("# comment"))
+	assert.Equal(t, []Event{{Type: EventLineChanged, Op: "add_raw", Line: 2, New: NewHostsLine("# comment")}}, events)
+
+	events = nil
+	hosts.RemoveByIP("10.0.0.1")
+	assert.Len(t, events, 3)
+	assert.Equal(t, Event{Type: EventEntryRemoved, Op: "remove_by_ip", IP: "10.0.0.1", Host: "app", Line: 1}, events[0])
+	assert.Equal(t, EventLineChanged, events[2].Type)
+	assert.Equal(t, HostsLine{}, events[2].New)
+
+	events = nil
+	assert.Nil(t, hosts.Flush())
+	assert.Equal(t, []Event{{Type: EventFlushed, Op: "flush"}}, events)
+
+	events = nil
+	assert.Nil(t, hosts.Load())
+	assert.Equal(t, []Event{{Type: EventReloaded, Op: "load"}}, events)
+
+	events = nil
+	unsubscribe()
+	assert.Nil(t, hosts.Add("10.0.0.2", "db"))
+	assert.Empty(t, events)
+}
+
+func TestHosts_SubscribeVeto(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(5))
+	errPolicy := errors.New("only .test hosts may be added")
+	hosts.Subscribe(func(e Event) error {
+		if e.Type == EventEntryAdded && !strings.HasSuffix(e.Host, ".test") {
+			return errPolicy
+		}
+		return nil
+	})
+	before := hosts.String()
+
+	assert.ErrorIs(t, hosts.Add("10.0.0.2", "db.test", "db"), errPolicy)
+	assert.Equal(t, before, hosts.String())
+	assert.False(t, hosts.HasHostname("db.test"))
+	assert.Empty(t, hosts.pending)
+	assert.False(t, hosts.CanUndo())
+
+	assert.Nil(t, hosts.Add("10.0.0.2", "db.test"))
+	assert.True(t, hosts.Has("10.0.0.2", "db.test"))
+	hosts.Clean()
+	assert.True(t, hosts.CanUndo())
+}
+
+func TestHosts_SubscribeVetoWithoutErrorReturn(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithClock(func() time.Time { return time.Unix(100, 0) }))
+	assert.Nil(t, hosts.AddUntil("10.0.0.2", time.Unix(50, 0), "old"))
+	errPolicy := errors.New("nothing may be removed")
+	hosts.Subscribe(func(e Event) error {
+		if e.Type == EventEntryRemoved {
+			return errPolicy
+		}
+		return nil
+	})
+	before := hosts.String()
+
+	hosts.RemoveByIP("10.0.0.1")
+	assert.ErrorIs(t, hosts.Err(), errPolicy)
+	assert.Equal(t, before, hosts.String())
+
+	hosts.SortIPs()
+	assert.Nil(t, hosts.Err())
+
+	assert.Nil(t, hosts.PruneExpired())
+	assert.ErrorIs(t, hosts.Err(), errPolicy)
+	assert.True(t, hosts.Has("10.0.0.2", "old"))
+}
+
+func TestHosts_SubscribeUndoRollback(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(5))
+	assert.Nil(t, hosts.Add("10.0.0.2", "db"))
+	var events []Event
+	hosts.Subscribe(func(e Event) error {
+		events = append(events, e)
+		if e.Type == EventEntryRemoved {
+			return errors.New("can't veto putting an edit back")
+		}
+		return nil
+	})
+
+	assert.True(t, hosts.Undo())
+	assert.False(t, hosts.HasHostname("db"))
+	assert.Equal(t, []Event{
+		{Type: EventEntryRemoved, Op: "undo", IP: "10.0.0.2", Host: "db", Line: 2},
+		{Type: EventLineChanged, Op: "undo", Line: 2, Old: NewHostsLine("10.0.0.2 db")},
+	}, events)
+
+	events = nil
+	assert.True(t, hosts.Redo())
+	assert.True(t, hosts.HasHostname("db"))
+	assert.Equal(t, Event{Type: EventEntryAdded, Op: "redo", IP: "10.0.0.2", Host: "db", Line: 2}, events[0])
+
+	tx := hosts.Begin()
+	assert.Nil(t, tx.Hosts.AddRaw("# comment")) // only entry events are vetoed by this hook
+	events = nil
+	assert.Nil(t, tx.Rollback())
+	assert.Equal(t, []Event{{Type: EventLineChanged, Op: "rollback", Line: 3, Old: NewHostsLine("# comment")}}, events)
+}
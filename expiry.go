@@ -0,0 +1,135 @@
+package hostsfile
+
+import (
+	"fmt"
+	"time"
+)
+
+// expiresKey is the metadata annotation holding when an entry expires e.g. "# @expires=2024-01-02T15:04:05Z"
+const expiresKey = "expires"
+
+// Expires returns when the line expires, false when it doesn't have a valid expiry
+func (l *HostsLine) Expires() (time.Time, bool) {
+	value, ok := l.Metadata[expiresKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	expires, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+// IsExpired returns true if the line has an expiry that's not after now
+func (l *HostsLine) IsExpired(now time.Time) bool {
+	expires, ok := l.Expires()
+	return ok && !expires.After(now)
+}
+
+// AddWithTTL adds the hosts to ip on their own line that expires after ttl, see PruneExpired
+func (h *Hosts) AddWithTTL(ip string, ttl time.Duration, hosts ...string) error {
+	return h.AddUntil(ip, h.clock().Add(ttl), hosts...)
+}
+
+// AddUntil adds the hosts to ip on their own line that expires at until, see PruneExpired. The hosts are removed from
+// any other line first so adding them again moves them to the new expiry.
+func (h *Hosts) AddUntil(ip string, until time.Time, hosts ...string) error {
+	return h.apply(edit{op: opAddUntil, ip: ip, hosts: hosts, time: until})
+}
+
+// WithClock sets the clock used by AddWithTTL and PruneExpired
+func WithClock(now func() time.Time) Option {
+	return func(h *Hosts) {
+		h.config.now = now
+	}
+}
+
+// PruneExpired removes the entry lines, including disabled ones, that have expired and returns what was removed.
+// Nothing is removed when a hook vetoes it, Err returns why.
+func (h *Hosts) PruneExpired() []HostsLine {
+	defer h.lockWrite()()
+	now := h.clock()
+	var removed []HostsLine
+	for _, line := range h.Lines {
+		if line.IP != "" && line.IsExpired(now) {
+			removed = append(removed, line)
+		}
+	}
+	h.lastErr = nil
+	if len(removed) > 0 {
+		if h.lastErr = h.applyEdit(edit{op: opPruneExpired, time: now}); h.lastErr != nil {
+			return nil
+		}
+	}
+	return removed
+}
+
+func (h *Hosts) clock() time.Time {
+	if h.config.now == nil {
+		return time.Now()
+	}
+	return h.config.now()
+}
+
+func (h *Hosts) addUntil(ip string, until time.Time, hosts ...string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts to add to %s", ip)
+	}
+	for _, host := range hosts {
+		if err := h.validateHost(host); err != nil {
+			return err
+		}
+	}
+
+	// remove hosts from wherever they already are
+	for _, host := range hosts {
+		var ips []string
+		for _, p := range h.hosts.get(host) {
+			if !h.inSection(p) && !itemInSliceString(h.Lines[p].IP, ips) {
+				ips = append(ips, h.Lines[p].IP)
+			}
+		}
+		for _, lineIP := range ips {
+			if err := h.remove(lineIP, host); err != nil {
+				return err
+			}
+		}
+	}
+
+	line := HostsLine{IP: ip, Hosts: hosts}
+	if err := line.SetMetadata(expiresKey, until.UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	h.addLine(line)
+	return nil
+}
+
+func (h *Hosts) pruneExpired(now time.Time) {
+	lines := make([]HostsLine, len(h.Lines))
+	copy(lines, h.Lines)
+	h.clear()
+
+	for _, line := range lines {
+		if line.IP != "" && line.IsExpired(now) {
+			continue
+		}
+		h.addLine(line)
+	}
+}
+
+// earliestExpiry sets the expiry of l to the earliest of l and other
+func (l *HostsLine) earliestExpiry(other HostsLine) {
+	expires, ok := other.Expires()
+	if !ok {
+		return
+	}
+	if current, ok := l.Expires(); ok && !expires.Before(current) {
+		return
+	}
+	l.ensureMetadata()
+	l.Metadata[expiresKey] = other.Metadata[expiresKey]
+}
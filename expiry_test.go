@@ -0,0 +1,85 @@
+package hostsfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_AddWithTTL(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n10.0.0.1 app debug\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithClock(func() time.Time { return now }))
+	assert.Nil(t, err)
+
+	assert.Nil(t, hosts.AddWithTTL("10.0.0.1", time.Hour, "debug"))
+	assert.Nil(t, hosts.AddUntil("10.0.0.2", now.Add(2*time.Hour), "db"))
+	assert.Nil(t, hosts.Add("10.0.0.1", "api"))
+	assert.Equal(t, "127.0.0.1 localhost\n10.0.0.1 app api\n10.0.0.1 debug # @expires=2024-01-02T16:04:05Z\n10.0.0.2 db # @expires=2024-01-02T17:04:05Z\n", hosts.String())
+	assert.NotNil(t, hosts.AddWithTTL("10.0.0.1", time.Hour))
+	assert.NotNil(t, hosts.AddWithTTL("10.0.0.1", time.Hour, "bad_host!"))
+
+	// survives a Flush and Load
+	assert.Nil(t, hosts.Flush())
+	expires, ok := hosts.Lines[2].Expires()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Hour), expires)
+
+	assert.Empty(t, hosts.PruneExpired())
+
+	now = now.Add(time.Hour)
+	removed := hosts.PruneExpired()
+	assert.Len(t, removed, 1)
+	assert.Equal(t, []string{"debug"}, removed[0].Hosts)
+	assert.False(t, hosts.HasHostname("debug"))
+	assert.True(t, hosts.Has("10.0.0.2", "db"))
+
+	now = now.Add(time.Hour)
+	assert.Len(t, hosts.PruneExpired(), 1)
+	assert.False(t, hosts.HasIP("10.0.0.2"))
+	assert.Len(t, hosts.Lines, 2)
+}
+
+func TestHosts_CombineKeepsExpiry(t *testing.T) {
+	hosts := loadHosts(t,
+		"10.0.0.1 a # @expires=2024-01-03T00:00:00Z",
+		"10.0.0.1 b # @expires=2024-01-02T00:00:00Z",
+		"10.0.0.1 c",
+		"10.0.0.1 d # @expires=2024-01-02T00:00:00Z",
+		"10.0.0.1 e",
+	)
+
+	// only lines with the same expiry are combined
+	hosts.CombineDuplicateIPs()
+	assert.Equal(t, "10.0.0.1 a # @expires=2024-01-03T00:00:00Z\n10.0.0.1 b d # @expires=2024-01-02T00:00:00Z\n10.0.0.1 c e\n", hosts.String())
+
+	// expiring lines keep the earliest expiry, permanent ones stay permanent
+	line := NewHostsLine("10.0.0.1 a # @expires=2024-01-03T00:00:00Z")
+	line.Combine(NewHostsLine("10.0.0.1 b # @expires=2024-01-02T00:00:00Z"))
+	assert.Equal(t, "10.0.0.1 a b # @expires=2024-01-02T00:00:00Z", line.Raw)
+	line = NewHostsLine("10.0.0.1 a # permanent")
+	line.Combine(NewHostsLine("10.0.0.1 b # @expires=2024-01-02T00:00:00Z"))
+	assert.Equal(t, "10.0.0.1 a b # permanent", line.Raw)
+	_, expires := line.Expires()
+	assert.False(t, expires)
+	line = NewHostsLine("10.0.0.1 a")
+	line.Combine(NewHostsLine("10.0.0.1 b # @expires=2024-01-02T00:00:00Z"))
+	assert.Equal(t, "10.0.0.1 a b", line.Raw)
+}
+
+func TestHosts_CleanKeepsPermanentHosts(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithClock(func() time.Time { return now }))
+	assert.Nil(t, err)
+
+	assert.Nil(t, hosts.AddWithTTL("127.0.0.1", time.Hour, "debug.test"))
+	hosts.Clean()
+	assert.Equal(t, "127.0.0.1 localhost\n127.0.0.1 debug.test # @expires=2024-01-02T16:04:05Z\n", hosts.String())
+
+	now = now.Add(time.Hour)
+	assert.Len(t, hosts.PruneExpired(), 1)
+	assert.True(t, hosts.Has("127.0.0.1", "localhost"))
+	assert.False(t, hosts.HasHostname("debug.test"))
+}
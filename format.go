@@ -0,0 +1,126 @@
+package hostsfile
+
+import (
+	"strings"
+)
+
+// FormatStyle is how entry lines are laid out by Format, comment and blank lines are never changed
+type FormatStyle int
+
+const (
+	// FormatPreserve leaves every line as it is
+	FormatPreserve FormatStyle = iota
+	// FormatSingleSpace separates the ip, hosts and trailing comment with single spaces
+	FormatSingleSpace
+	// FormatTabs separates the ip from the hosts and the hosts from the trailing comment with a tab
+	FormatTabs
+	// FormatAligned pads the ips and hosts so the hosts and trailing comments line up in columns, each block of entry
+	// lines between comment or blank lines is aligned on its own so a change only reformats its own block
+	FormatAligned
+	// FormatOneHostPerLine splits lines so every host gets its own line, keeping the trailing comment on each, and
+	// aligns them the same as FormatAligned
+	FormatOneHostPerLine
+)
+
+// Format rewrites the entry lines in style
+func (h *Hosts) Format(style FormatStyle) {
+	h.applyQuiet(edit{op: opFormat, style: style})
+}
+
+// WithFormat formats the hosts file in style on every Flush
+func WithFormat(style FormatStyle) Option {
+	return func(h *Hosts) {
+		h.config.format = style
+	}
+}
+
+func (h *Hosts) format(style FormatStyle) {
+	if style == FormatPreserve {
+		return
+	}
+
+	lines := h.Lines
+	if style == FormatOneHostPerLine {
+		lines = make([]HostsLine, 0, len(h.Lines))
+		for _, line := range h.Lines {
+			if !isEntryLine(line) || len(line.Hosts) == 1 {
+				lines = append(lines, line)
+				continue
+			}
+			comment := line.metadataComment()
+			for _, host := range line.Hosts {
+				lines = append(lines, HostsLine{IP: line.IP, Hosts: []string{host}, Comment: comment, Metadata: parseMetadata(comment)})
+			}
+		}
+	}
+
+	// format each block of consecutive entry lines together
+	for start := 0; start < len(lines); {
+		if !isFormattable(lines[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && isFormattable(lines[end]) {
+			end++
+		}
+		formatBlock(lines[start:end], style)
+		start = end
+	}
+
+	if len(lines) != len(h.Lines) {
+		h.Lines = lines
+		h.reindex()
+	}
+}
+
+// isFormattable is true for lines with a valid ip, comments, blanks and malformed lines are left alone
+func isFormattable(line HostsLine) bool {
+	return !line.IsComment() && !line.IsMalformed() && line.IP != ""
+}
+
+func formatBlock(block []HostsLine, style FormatStyle) {
+	ipWidth, hostsWidth := 0, 0
+	for _, line := range block {
+		if len(line.IP) > ipWidth {
+			ipWidth = len(line.IP)
+		}
+		if w := len(strings.Join(line.Hosts, " ")); w > hostsWidth {
+			hostsWidth = w
+		}
+	}
+
+	for i := range block {
+		line := &block[i]
+		hosts := strings.Join(line.Hosts, " ")
+
+		var b strings.Builder
+		b.WriteString(line.IP)
+		switch style {
+		case FormatTabs:
+			if hosts != "" {
+				b.WriteString("\t" + hosts)
+			}
+			if line.Comment != "" {
+				b.WriteString("\t" + commentChar + line.Comment)
+			}
+		case FormatAligned, FormatOneHostPerLine:
+			if hosts != "" || line.Comment != "" {
+				b.WriteString(strings.Repeat(" ", ipWidth-len(line.IP)+1))
+				b.WriteString(hosts)
+			}
+			if line.Comment != "" {
+				b.WriteString(strings.Repeat(" ", hostsWidth-len(hosts)+1))
+				b.WriteString(commentChar + line.Comment)
+			}
+		default:
+			if hosts != "" {
+				b.WriteString(" " + hosts)
+			}
+			if line.Comment != "" {
+				b.WriteString(" " + commentChar + line.Comment)
+			}
+		}
+		line.Raw = b.String()
+	}
+}
@@ -0,0 +1,136 @@
+package hostsfile
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFormatHosts(t *testing.T) *Hosts {
+	return loadHosts(t,
+		"# header",
+		"127.0.0.1    localhost",
+		"  ::1 localhost   ip6-localhost # loopback",
+		"",
+		"10.0.0.1\tapp",
+		"255.255.255.255 broadcasthost #bcast",
+		"fe00::0 ",
+		"bad line",
+	)
+}
+
+func TestHosts_Format(t *testing.T) {
+	tests := []struct {
+		style    FormatStyle
+		expected []string
+	}{
+		{
+			style: FormatPreserve,
+			expected: []string{
+				"# header",
+				"127.0.0.1    localhost",
+				"  ::1 localhost   ip6-localhost # loopback",
+				"",
+				"10.0.0.1\tapp",
+				"255.255.255.255 broadcasthost #bcast",
+				"fe00::0 ",
+				"bad line",
+			},
+		},
+		{
+			style: FormatSingleSpace,
+			expected: []string{
+				"# header",
+				"127.0.0.1 localhost",
+				"::1 localhost ip6-localhost # loopback",
+				"",
+				"10.0.0.1 app",
+				"255.255.255.255 broadcasthost #bcast",
+				"fe00::0",
+				"bad line",
+			},
+		},
+		{
+			style: FormatTabs,
+			expected: []string{
+				"# header",
+				"127.0.0.1\tlocalhost",
+				"::1\tlocalhost ip6-localhost\t# loopback",
+				"",
+				"10.0.0.1\tapp",
+				"255.255.255.255\tbroadcasthost\t#bcast",
+				"fe00::0",
+				"bad line",
+			},
+		},
+		{
+			style: FormatAligned,
+			expected: []string{
+				"# header",
+				"127.0.0.1 localhost",
+				"::1       localhost ip6-localhost # loopback",
+				"",
+				"10.0.0.1        app",
+				"255.255.255.255 broadcasthost #bcast",
+				"fe00::0",
+				"bad line",
+			},
+		},
+		{
+			style: FormatOneHostPerLine,
+			expected: []string{
+				"# header",
+				"127.0.0.1 localhost",
+				"::1       localhost     # loopback",
+				"::1       ip6-localhost # loopback",
+				"",
+				"10.0.0.1        app",
+				"255.255.255.255 broadcasthost #bcast",
+				"fe00::0",
+				"bad line",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		hosts := newFormatHosts(t)
+		hosts.Format(tt.style)
+		assert.Equal(t, strings.Join(tt.expected, eol)+eol, hosts.String())
+		assert.True(t, hosts.Has("::1", "ip6-localhost"))
+
+		// formatting twice changes nothing
+		hosts.Format(tt.style)
+		assert.Equal(t, strings.Join(tt.expected, eol)+eol, hosts.String())
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithPath("hosts"), WithStorage(storage), WithFormat(FormatAligned))
+	assert.Nil(t, err)
+
+	assert.Nil(t, hosts.Add("255.255.255.255", "broadcasthost"))
+	assert.Nil(t, hosts.Flush())
+	assert.Equal(t, "127.0.0.1       localhost\n255.255.255.255 broadcasthost\n", readStorage(t, storage, "hosts"))
+}
+
+func TestHosts_FormatKeepsMetadata(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	hosts := loadHosts(t, "10.0.0.1 app api # dev @owner=ci @expires=2000-01-01T00:00:00Z", "127.0.0.1 localhost")
+	WithClock(func() time.Time { return now })(hosts)
+
+	for _, style := range []FormatStyle{FormatOneHostPerLine, FormatAligned, FormatTabs} {
+		hosts.Format(style)
+		assert.Len(t, hosts.FindByMetadata("owner", "ci"), 2, style)
+
+		reloaded := loadHosts(t, strings.TrimSuffix(hosts.String(), eol))
+		for i, line := range hosts.Lines {
+			assert.Equal(t, line.Metadata, reloaded.Lines[i].Metadata, style)
+		}
+	}
+
+	assert.Len(t, hosts.PruneExpired(), 2)
+	assert.Equal(t, "127.0.0.1\tlocalhost"+eol, hosts.String())
+}
@@ -0,0 +1,109 @@
+package hostsfile
+
+// history holds the states before each edit so they can be undone, and the states undone so they can be redone
+type history struct {
+	undo []historyEntry
+	redo []historyEntry
+}
+
+type historyEntry struct {
+	state      hostsState
+	generation int // the Load the state belongs to
+}
+
+// WithHistory keeps the last depth edits so they can be undone with Undo, 0 disables the history
+func WithHistory(depth int) Option {
+	return func(h *Hosts) {
+		h.config.historyDepth = depth
+	}
+}
+
+// WithHistoryAcrossFlush keeps the history after Flush so edits that were already written can still be undone, by
+// default Flush resets the history
+func WithHistoryAcrossFlush(keep bool) Option {
+	return func(h *Hosts) {
+		h.config.keepHistory = keep
+	}
+}
+
+// Undo reverts the last edit, it returns false when there is nothing to undo
+func (h *Hosts) Undo() bool {
+	defer h.lockWrite()()
+	if len(h.history.undo) == 0 {
+		return false
+	}
+	entry := h.history.undo[len(h.history.undo)-1]
+	h.history.undo = h.history.undo[:len(h.history.undo)-1]
+	h.history.redo = append(h.history.redo, historyEntry{state: h.state(), generation: h.generation})
+	h.restoreEntry("undo", entry)
+	return true
+}
+
+// Redo makes the last undone edit again, it returns false when there is nothing to redo
+func (h *Hosts) Redo() bool {
+	defer h.lockWrite()()
+	if len(h.history.redo) == 0 {
+		return false
+	}
+	entry := h.history.redo[len(h.history.redo)-1]
+	h.history.redo = h.history.redo[:len(h.history.redo)-1]
+	h.history.undo = append(h.history.undo, historyEntry{state: h.state(), generation: h.generation})
+	h.restoreEntry("redo", entry)
+	return true
+}
+
+// CanUndo returns true if there is an edit to undo
+func (h *Hosts) CanUndo() bool {
+	defer h.lockRead()()
+	return len(h.history.undo) > 0
+}
+
+// CanRedo returns true if there is an undone edit to redo
+func (h *Hosts) CanRedo() bool {
+	defer h.lockRead()()
+	return len(h.history.redo) > 0
+}
+
+// ResetHistory forgets all the edits that could be undone or redone
+func (h *Hosts) ResetHistory() {
+	defer h.lockWrite()()
+	h.history = history{}
+}
+
+// restoreEntry puts back the lines of entry. When the entry is from before the last Load the pending edits can't be
+// rewound, the restored lines are recorded as an edit instead so reapplying or planning still gives the same result.
+func (h *Hosts) restoreEntry(op editOp, entry historyEntry) {
+	if entry.generation == h.generation {
+		h.restoreWithEvents(op, entry.state)
+		return
+	}
+
+	pending := append(h.pending, edit{op: opSetLines, hosts: rawLines(entry.state.lines)})
+	h.restoreWithEvents(op, entry.state)
+	h.pending = pending
+}
+
+// push records the state before an edit, dropping the oldest once there are more than depth, a new edit can't be
+// redone on top of so the redo stack is cleared
+func (hi *history) push(state hostsState, generation, depth int) {
+	hi.undo = append(hi.undo, historyEntry{state: state, generation: generation})
+	if len(hi.undo) > depth {
+		hi.undo = append([]historyEntry(nil), hi.undo[len(hi.undo)-depth:]...)
+	}
+	hi.redo = nil
+}
+
+func (hi *history) copy() history {
+	return history{
+		undo: append([]historyEntry(nil), hi.undo...),
+		redo: append([]historyEntry(nil), hi.redo...),
+	}
+}
+
+// setLines replaces all the lines with raw
+func (h *Hosts) setLines(raw []string) {
+	h.clear()
+	for _, r := range raw {
+		h.addLine(NewHostsLine(r))
+	}
+}
@@ -0,0 +1,101 @@
+package hostsfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHistoryHosts(t *testing.T, opts ...Option) (*Hosts, *MemoryStorage) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n10.0.0.1 app\n")})
+	hosts, err := New(append([]Option{WithStorage(storage), WithPath("hosts")}, opts...)...)
+	assert.Nil(t, err)
+	return hosts, storage
+}
+
+func TestHosts_UndoRedo(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(10))
+	original := hosts.String()
+	assert.False(t, hosts.CanUndo())
+	assert.False(t, hosts.Undo())
+
+	assert.Nil(t, hosts.Add("10.0.0.2", "db"))
+	assert.Nil(t, hosts.RemoveByHostname("app"))
+	assert.Nil(t, hosts.ReplaceLine(0, "127.0.0.1 localhost local"))
+	hosts.Clean()
+	cleaned := hosts.String()
+
+	assert.True(t, hosts.Undo())
+	assert.True(t, hosts.Undo())
+	assert.Equal(t, "127.0.0.1 localhost\n10.0.0.2 db\n", hosts.String())
+	assert.True(t, hosts.Undo())
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.True(t, hosts.Undo())
+	assert.Equal(t, original, hosts.String())
+	assert.False(t, hosts.HasIP("10.0.0.2"))
+	assert.Empty(t, hosts.pending)
+	assert.False(t, hosts.Undo())
+
+	for hosts.Redo() {
+	}
+	assert.Equal(t, cleaned, hosts.String())
+	assert.True(t, hosts.Has("127.0.0.1", "local"))
+	assert.Len(t, hosts.pending, 4)
+
+	// a new edit drops what could be redone
+	assert.True(t, hosts.Undo())
+	assert.True(t, hosts.CanRedo())
+	hosts.RemoveByIP("10.0.0.2")
+	assert.False(t, hosts.CanRedo())
+
+	assert.NotNil(t, hosts.ReplaceLine(10, "10.0.0.3 x"))
+	assert.NotNil(t, hosts.ReplaceLine(0, "10.0.0.3 bad_host!"))
+}
+
+func TestHosts_HistoryDepth(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(2))
+	assert.Nil(t, hosts.Add("10.0.0.2", "a"))
+	assert.Nil(t, hosts.Add("10.0.0.3", "b"))
+	assert.Nil(t, hosts.Add("10.0.0.4", "c"))
+
+	assert.True(t, hosts.Undo())
+	assert.True(t, hosts.Undo())
+	assert.False(t, hosts.Undo())
+	assert.True(t, hosts.HasIP("10.0.0.2"))
+
+	disabled, _ := newHistoryHosts(t)
+	assert.Nil(t, disabled.Add("10.0.0.2", "a"))
+	assert.False(t, disabled.CanUndo())
+}
+
+func TestHosts_HistoryFlush(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(10))
+	assert.Nil(t, hosts.Add("10.0.0.2", "db"))
+	assert.Nil(t, hosts.Flush())
+	assert.False(t, hosts.CanUndo())
+
+	kept, storage := newHistoryHosts(t, WithHistory(10), WithHistoryAcrossFlush(true))
+	assert.Nil(t, kept.Add("10.0.0.2", "db"))
+	assert.Nil(t, kept.Flush())
+	assert.True(t, kept.Undo())
+	assert.False(t, kept.HasIP("10.0.0.2"))
+
+	// the undo is written like any other edit
+	assert.Nil(t, kept.Flush())
+	assert.Equal(t, "127.0.0.1 localhost\n10.0.0.1 app\n", readStorage(t, storage, "hosts"))
+	assert.True(t, kept.Redo())
+	assert.True(t, kept.HasIP("10.0.0.2"))
+}
+
+func TestHosts_HistoryTransaction(t *testing.T) {
+	hosts, _ := newHistoryHosts(t, WithHistory(10))
+	assert.Nil(t, hosts.Add("10.0.0.2", "db"))
+
+	tx := hosts.Begin()
+	assert.Nil(t, tx.Add("10.0.0.3", "cache"))
+	assert.Nil(t, tx.Rollback())
+
+	assert.True(t, hosts.Undo())
+	assert.False(t, hosts.CanUndo())
+	assert.False(t, hosts.HasIP("10.0.0.2"))
+}
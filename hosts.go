@@ -3,38 +3,54 @@ package hostsfile
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/asaskevich/govalidator"
 	"github.com/dimchansky/utfbom"
 )
 
 // Hosts represents hosts file with the path and parsed contents of each line
 type Hosts struct {
-	Path    string      // Path to the location of the hosts file that will be loaded/flushed
-	Lines   []HostsLine // Slice containing all the lines parsed from the hosts file
-	modTime time.Time   // Track file modification time
+	Path       string         // Path to the location of the hosts file that will be loaded/flushed
+	Lines      []HostsLine    // Slice containing all the lines parsed from the hosts file
+	Conflict   ConflictMode   // What Flush does when the file was changed on disk since Load
+	AutoBackup *BackupManager // When set a backup of the hosts file is taken before every Flush
+	EOL        string         // Line ending used when writing, detected on Load and uses the platform default when empty
+	BOM        bool           // Write a UTF-8 byte order mark at the start of the file, set on Load if the file had one
+	modTime    time.Time      // Track file modification time
+	checksum   []byte         // sha256 of the file contents at Load, mtime alone is too coarse on some filesystems
+	pending    []edit         // Edits made since Load
+	base       []HostsLine    // Lines as they were at Load, used to merge with changes made on disk
+	missingEOL bool           // The file didn't end with a newline
+	lock       func() error   // Releases the lock held between Lock and Unlock
+	storage    Storage        // Where the hosts file is read from and written to, disk when nil
+	config     config         // Settings made with Options
+	history    history        // Undo and redo stacks, see WithHistory
+	generation int            // Incremented on every Load so history can tell which file an edit was made against
+	replaying  bool           // Pending edits are being replayed and shouldn't be recorded in the history again
+	hooks      []subscription // Added with Subscribe
+	mu         *sync.RWMutex  // Set by WithConcurrencySafe, nil when the Hosts is only used from one goroutine
+	nextHookID int
+	lastErr    error // Returned by Err
 
 	ips   lookup
 	hosts lookup
+
+	snapshot atomic.Pointer[Snapshot] // Published for lock-free readers, see WithSnapshots
 }
 
 // NewHosts return a new instance of Hosts using the default hosts file path.
 func NewHosts() (*Hosts, error) {
-	osHostsFilePath := os.ExpandEnv(filepath.FromSlash(HostsFilePath))
-
-	if env, isset := os.LookupEnv("HOSTS_PATH"); isset && len(env) > 0 {
-		osHostsFilePath = os.ExpandEnv(filepath.FromSlash(env))
-	}
-
-	return NewCustomHosts(osHostsFilePath)
+	return NewCustomHosts(defaultHostsFilePath())
 }
 
 // NewCustomHosts return a new instance of Hosts using a custom hosts file path.
@@ -52,91 +68,227 @@ func NewCustomHosts(osHostsFilePath string) (*Hosts, error) {
 	return hosts, nil
 }
 
+// NewHostsWithStorage return a new instance of Hosts reading and writing the hosts file at path through storage.
+func NewHostsWithStorage(path string, storage Storage) (*Hosts, error) {
+	hosts := &Hosts{
+		Path:    path,
+		storage: storage,
+		ips:     newLookup(),
+		hosts:   newLookup(),
+	}
+
+	if err := hosts.Load(); err != nil {
+		return hosts, err
+	}
+
+	return hosts, nil
+}
+
+// Storage returns the Storage the hosts file is read from and written to
+func (h *Hosts) Storage() Storage {
+	if h.storage == nil {
+		return DiskStorage{}
+	}
+	return h.storage
+}
+
+// newHostsFromLines returns a Hosts for path made up of lines, nothing is loaded from disk
+func newHostsFromLines(path string, lines []HostsLine) *Hosts {
+	h := &Hosts{
+		Path:  path,
+		ips:   newLookup(),
+		hosts: newLookup(),
+	}
+	h.clear()
+	for _, line := range lines {
+		h.addLine(line)
+	}
+	return h
+}
+
 // String get a string of the contents of the contents to put in the hosts file
 func (h *Hosts) String() string {
+	defer h.lockRead()()
 	buf := new(bytes.Buffer)
 	for _, line := range h.Lines {
 		// bytes buffers doesn't actually throw errors but the io.Writer interface requires it
-		fmt.Fprintf(buf, "%s%s", line.ToRaw(), eol)
+		fmt.Fprintf(buf, "%s%s", line.ToRaw(), h.lineEnding())
 	}
 	return buf.String()
 }
 
-// loadString is a helper function for testing but if we want to expose it somehow it's probably safe
+// NewHostsFromReader returns a new instance of Hosts with the contents read from r, Path is left empty so Flush can't be
+// used until it's set.
+func NewHostsFromReader(r io.Reader) (*Hosts, error) {
+	hosts := &Hosts{
+		ips:   newLookup(),
+		hosts: newLookup(),
+	}
+
+	if _, err := hosts.ReadFrom(r); err != nil {
+		return hosts, err
+	}
+
+	return hosts, nil
+}
+
+// loadString is a helper function for testing
 func (h *Hosts) loadString(content string) error {
-	h.Clear()
-	rdr := strings.NewReader(content)
-	scanner := bufio.NewScanner(utfbom.SkipOnly(rdr))
-	for scanner.Scan() {
-		h.addLine(NewHostsLine(scanner.Text()))
+	_, err := h.ReadFrom(strings.NewReader(content))
+	return err
+}
+
+// ReadFrom replaces Lines with the hosts file contents read from r, skipping a leading UTF-8 BOM the same as Load.
+func (h *Hosts) ReadFrom(r io.Reader) (int64, error) {
+	defer h.lockWrite()()
+	return h.parse(r)
+}
+
+// WriteTo writes the contents of Lines to w exactly as Flush would write them to disk, Lines are left untouched.
+func (h *Hosts) WriteTo(w io.Writer) (int64, error) {
+	defer h.lockRead()()
+	out := newHostsFromLines(h.Path, h.Lines)
+	out.config = h.config
+	if err := out.preFlush(); err != nil {
+		return 0, err
 	}
-	return scanner.Err()
+
+	bw := bufio.NewWriter(w)
+	n, err := h.writeLines(bw, out.Lines)
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
 }
 
 // IsWritable return true if hosts file is writable.
 func (h *Hosts) IsWritable() bool {
-	file, err := os.OpenFile(h.Path, os.O_WRONLY, 0660)
-	if err != nil {
-		return false
+	if w, ok := h.Storage().(writableStorage); ok {
+		return w.IsWritable(h.Path)
 	}
-	defer file.Close()
 	return true
 }
 
 // Load the hosts file from the Path into Lines, called by NewHosts() and Hosts.Flush() and you should not need to call this yourself.
 func (h *Hosts) Load() error {
-	file, err := os.Open(h.Path)
-	if err != nil {
+	defer h.lockWrite()()
+	return h.reload()
+}
+
+// reload loads the hosts file and sends the reloaded event
+func (h *Hosts) reload() error {
+	if err := h.load(); err != nil {
 		return err
 	}
-	defer file.Close()
+	return h.emit(Event{Type: EventReloaded, Op: "load"})
+}
 
+func (h *Hosts) load() error {
 	// Capture modification time for concurrent modification detection
-	info, err := file.Stat()
+	info, err := h.Storage().Stat(h.Path)
 	if err != nil {
 		return err
 	}
 	h.modTime = info.ModTime()
 
-	h.Clear() // reset the lines and lookups in case anything was previously set
+	file, err := h.Storage().Open(h.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sum := sha256.New()
+	if _, err := h.parse(io.TeeReader(file, sum)); err != nil {
+		return err
+	}
 
-	scanner := bufio.NewScanner(utfbom.SkipOnly(file))
+	h.checksum = sum.Sum(nil)
+	return nil
+}
+
+// parse replaces Lines with the lines read from r and returns the number of bytes read
+func (h *Hosts) parse(r io.Reader) (int64, error) {
+	defer h.publish()
+	h.clear() // reset the lines and lookups in case anything was previously set
+	h.pending = nil
+	h.generation++
+
+	tr := &trackingReader{r: r}
+	rdr, enc := utfbom.Skip(tr)
+	h.BOM = enc == utfbom.UTF8
+	if h.config.bom != nil {
+		h.BOM = *h.config.bom
+	}
+	scanner := bufio.NewScanner(rdr)
 	for scanner.Scan() {
 		h.addLine(NewHostsLine(scanner.Text()))
 	}
+	if err := scanner.Err(); err != nil {
+		return tr.n, err
+	}
 
-	return scanner.Err()
+	// remember a missing newline at the end of the file so writing it back doesn't add one
+	h.missingEOL = tr.n > 0 && tr.last != '\n'
+	if h.config.eol != "" {
+		h.EOL = h.config.eol
+	} else if tr.eol != "" {
+		h.EOL = tr.eol
+	}
+	h.base = make([]HostsLine, len(h.Lines))
+	copy(h.base, h.Lines)
+	return tr.n, nil
 }
 
-// HasBeenModified checks if the hosts file was modified since it was loaded
+// HasBeenModified checks if the hosts file was modified since it was loaded, comparing both the modification time and
+// the contents of the file
 func (h *Hosts) HasBeenModified() (bool, error) {
-	info, err := os.Stat(h.Path)
+	defer h.lockRead()()
+	return h.hasBeenModified()
+}
+
+func (h *Hosts) hasBeenModified() (bool, error) {
+	info, err := h.Storage().Stat(h.Path)
 	if err != nil {
 		return false, err
 	}
-	return !info.ModTime().Equal(h.modTime), nil
+	if !info.ModTime().Equal(h.modTime) || h.checksum == nil {
+		return true, nil
+	}
+	return h.contentChanged()
 }
 
-// Flush writes to the file located at Path the contents of Lines in a hostsfile format
+// Flush writes to the file located at Path the contents of Lines in a hostsfile format. The contents are written to a
+// temp file first and renamed over Path so the hosts file is never left empty or half written. If the file was changed
+// on disk since it was loaded Flush returns ErrConcurrentModification, or replays the edits on the new contents when
+// Conflict is set to ConflictReapply.
 func (h *Hosts) Flush() error {
-	if err := h.preFlush(); err != nil {
-		return err
-	}
+	defer h.lockWrite()()
+	return h.flush()
+}
 
-	file, err := os.Create(h.Path)
-	if err != nil {
+func (h *Hosts) flush() error {
+	if err := h.checkConflict(); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	w := bufio.NewWriter(file)
-	for _, line := range h.Lines {
-		if _, err := fmt.Fprintf(w, "%s%s", line.ToRaw(), eol); err != nil {
+	if h.AutoBackup != nil {
+		if !isDiskStorage(h.Storage()) {
+			return ErrBackupUnsupported
+		}
+		if _, err := h.AutoBackup.Create(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 	}
 
-	if err := w.Flush(); err != nil {
+	if err := h.preFlush(); err != nil {
+		return err
+	}
+
+	err := h.Storage().WriteFile(h.Path, func(w io.Writer) error {
+		_, err := h.writeLines(w, h.Lines)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
@@ -144,7 +296,13 @@ func (h *Hosts) Flush() error {
 		return err
 	}
 
-	return h.Load()
+	if !h.config.keepHistory {
+		h.history = history{}
+	}
+	if err := h.load(); err != nil {
+		return err
+	}
+	return h.emit(Event{Type: EventFlushed, Op: "flush"})
 }
 
 // BackupPath returns the default backup path for the hosts file
@@ -159,33 +317,35 @@ func (h *Hosts) Backup() error {
 
 // BackupTo creates a backup of the hosts file to a specified path
 func (h *Hosts) BackupTo(path string) error {
-	source, err := os.Open(h.Path)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
+	return h.Storage().Backup(h.Path, path)
+}
 
-	dest, err := os.Create(path)
-	if err != nil {
+// Restore replaces the hosts file with a backup taken by a BackupManager and reloads it
+func (h *Hosts) Restore(backup BackupInfo) error {
+	defer h.lockWrite()()
+	if err := h.BackupManager("").Restore(backup); err != nil {
 		return err
 	}
-	defer dest.Close()
-
-	_, err = io.Copy(dest, source)
-	return err
+	return h.reload()
 }
 
 // AddRaw takes a line from a hosts file and parses/adds the HostsLine
 func (h *Hosts) AddRaw(raw ...string) error {
+	return h.apply(edit{op: opAddRaw, hosts: raw})
+}
+
+func (h *Hosts) addRaw(raw ...string) error {
 	for _, r := range raw {
 		nl := NewHostsLine(r)
-		if nl.IP != "" && net.ParseIP(nl.IP) == nil {
-			return fmt.Errorf("%q is an invalid IP address", nl.IP)
+		if nl.IP != "" {
+			if err := h.validateIP(nl.IP); err != nil {
+				return err
+			}
 		}
 
 		for _, host := range nl.Hosts {
-			if !govalidator.IsDNSName(host) {
-				return fmt.Errorf("hostname is not a valid dns name: %s", host)
+			if err := h.validateHost(host); err != nil {
+				return err
 			}
 		}
 		h.addLine(nl)
@@ -194,26 +354,72 @@ func (h *Hosts) AddRaw(raw ...string) error {
 	return nil
 }
 
+// ReplaceLine replaces the line at pos with raw, parsed and validated the same as AddRaw
+func (h *Hosts) ReplaceLine(pos int, raw string) error {
+	return h.apply(edit{op: opReplaceLine, line: pos, hosts: []string{raw}})
+}
+
+// replaceLine replaces the line at pos and returns what it was, when old is set the line has to still be old so
+// replaying it on a changed file can't overwrite someone else's line
+func (h *Hosts) replaceLine(pos int, raw string, old *string) (*string, error) {
+	if pos < 0 || pos >= len(h.Lines) {
+		return old, fmt.Errorf("line %d out of range", pos)
+	}
+	current := h.Lines[pos].ToRaw()
+	if old != nil && *old != current {
+		return old, fmt.Errorf("line %d is no longer %q: %w", pos, *old, ErrConcurrentModification)
+	}
+	line := NewHostsLine(raw)
+	if line.IP != "" && !line.IsComment() {
+		if err := h.validateIP(line.IP); err != nil {
+			return old, err
+		}
+		for _, host := range line.Hosts {
+			if err := h.validateHost(host); err != nil {
+				return old, err
+			}
+		}
+	}
+	h.Lines[pos] = line
+	h.reindex()
+	return &current, nil
+}
+
 // Add an entry to the hosts file.
 func (h *Hosts) Add(ip string, hosts ...string) error {
-	if net.ParseIP(ip) == nil {
-		return fmt.Errorf("%q is an invalid IP address", ip)
+	return h.apply(edit{op: opAdd, ip: ip, hosts: hosts})
+}
+
+func (h *Hosts) add(ip string, hosts ...string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if err := h.validateHost(host); err != nil {
+			return err
+		}
 	}
 
 	// remove hosts from other ips if it already exists
 	for _, host := range hosts {
 		for _, p := range h.hosts.get(host) {
-			if h.Lines[p].IP == ip {
+			if h.Lines[p].IP == ip || h.inSection(p) {
 				continue
 			}
 
-			if err := h.Remove(h.Lines[p].IP, host); err != nil {
+			if err := h.remove(h.Lines[p].IP, host); err != nil {
 				return err
 			}
 		}
 	}
 
-	position := h.ips.get(ip)
+	// never add to a line inside a section, those are managed through Section, or to one that expires
+	var position []int
+	for _, p := range h.ips.get(ip) {
+		if _, expires := h.Lines[p].Expires(); !h.inSection(p) && !expires {
+			position = append(position, p)
+		}
+	}
 	if len(position) == 0 {
 		h.addLine(HostsLine{
 			Raw:   fmt.Sprintf("%s %s", ip, strings.Join(hosts, " ")),
@@ -227,14 +433,10 @@ func (h *Hosts) Add(ip string, hosts ...string) error {
 		copy(hostsCopy, h.Lines[loc].Hosts)
 
 		for _, addHost := range hosts {
-			if h.Has(ip, addHost) {
+			if h.has(ip, addHost) {
 				continue // this combo already exists
 			}
 
-			if !govalidator.IsDNSName(addHost) {
-				return fmt.Errorf("hostname is not a valid dns name: %s", addHost)
-			}
-
 			hostsCopy = append(hostsCopy, addHost)
 			h.hosts.add(addHost, loc)
 		}
@@ -245,7 +447,12 @@ func (h *Hosts) Add(ip string, hosts ...string) error {
 	return nil
 }
 
+// Clear removes all lines
 func (h *Hosts) Clear() {
+	h.applyQuiet(edit{op: opClear})
+}
+
+func (h *Hosts) clear() {
 	h.Lines = []HostsLine{}
 	h.ips.reset()
 	h.hosts.reset()
@@ -253,15 +460,53 @@ func (h *Hosts) Clear() {
 
 // Clean merge duplicate ips and hosts per ip
 func (h *Hosts) Clean() {
-	h.CombineDuplicateIPs()
-	h.RemoveDuplicateHosts()
-	h.SortHosts()
-	h.SortIPs()
-	h.HostsPerLine(HostsPerLine)
+	h.applyQuiet(edit{op: opClean})
+}
+
+func (h *Hosts) clean() {
+	outside, sections := splitSections(h.Lines)
+	if len(sections) == 0 {
+		h.cleanLines()
+		return
+	}
+
+	// clean each section on its own so the markers and what's in them stay together, sections go after everything else
+	lines := cleanLines(outside)
+	for _, section := range sections {
+		lines = append(lines, section[0])
+		end := len(section)
+		if kind, _ := sectionMarker(section[end-1]); kind == sectionEnd && end > 1 {
+			end--
+		}
+		lines = append(lines, cleanLines(section[1:end])...)
+		lines = append(lines, section[end:]...)
+	}
+
+	h.Lines = lines
+	h.reindex()
+}
+
+func (h *Hosts) cleanLines() {
+	h.combineDuplicateIPs()
+	h.removeDuplicateHosts()
+	h.sortHosts()
+	h.sortIPs()
+	h.hostsPerLine(h.maxHostsPerLine())
+}
+
+func cleanLines(lines []HostsLine) []HostsLine {
+	h := newHostsFromLines("", lines)
+	h.cleanLines()
+	return h.Lines
 }
 
 // Has return a bool if ip/host combo exists in the Lines
 func (h *Hosts) Has(ip string, host string) bool {
+	defer h.lockRead()()
+	return h.has(ip, host)
+}
+
+func (h *Hosts) has(ip string, host string) bool {
 	ippos := h.ips.get(ip)
 	hostpos := h.hosts.get(host)
 	for _, pos := range ippos {
@@ -271,12 +516,16 @@ func (h *Hosts) Has(ip string, host string) bool {
 		}
 	}
 
-	return false
+	return h.config.includeDisabled && len(h.disabledLines(ip, host)) > 0
 }
 
 // HasHostname return a bool if hostname in hosts file.
 func (h *Hosts) HasHostname(host string) bool {
-	return len(h.hosts.get(host)) > 0
+	defer h.lockRead()()
+	if len(h.hosts.get(host)) > 0 {
+		return true
+	}
+	return h.config.includeDisabled && len(h.disabledLines("", host)) > 0
 }
 
 // Deprecated: HasIp will be replaced by HasIP
@@ -286,16 +535,25 @@ func (h *Hosts) HasIp(ip string) bool {
 
 // HasIP will check if the ip exists
 func (h *Hosts) HasIP(ip string) bool {
-	return len(h.ips.get(ip)) > 0
+	defer h.lockRead()()
+	return h.hasIP(ip)
+}
+
+func (h *Hosts) hasIP(ip string) bool {
+	if len(h.ips.get(ip)) > 0 {
+		return true
+	}
+	return h.config.includeDisabled && len(h.disabledLines(ip, "")) > 0
 }
 
 // HasAll returns true if the IP has ALL specified hostnames mapped to it
 func (h *Hosts) HasAll(ip string, hosts ...string) bool {
+	defer h.lockRead()()
 	if len(hosts) == 0 {
-		return h.HasIP(ip)
+		return h.hasIP(ip)
 	}
 	for _, host := range hosts {
-		if !h.Has(ip, host) {
+		if !h.has(ip, host) {
 			return false
 		}
 	}
@@ -304,11 +562,12 @@ func (h *Hosts) HasAll(ip string, hosts ...string) bool {
 
 // HasAny returns true if the IP has ANY of the specified hostnames mapped to it
 func (h *Hosts) HasAny(ip string, hosts ...string) bool {
+	defer h.lockRead()()
 	if len(hosts) == 0 {
-		return h.HasIP(ip)
+		return h.hasIP(ip)
 	}
 	for _, host := range hosts {
-		if h.Has(ip, host) {
+		if h.has(ip, host) {
 			return true
 		}
 	}
@@ -317,9 +576,10 @@ func (h *Hosts) HasAny(ip string, hosts ...string) bool {
 
 // CheckAll returns a map indicating which hostnames exist for the IP
 func (h *Hosts) CheckAll(ip string, hosts ...string) map[string]bool {
+	defer h.lockRead()()
 	result := make(map[string]bool)
 	for _, host := range hosts {
-		result[host] = h.Has(ip, host)
+		result[host] = h.has(ip, host)
 	}
 	return result
 }
@@ -327,8 +587,12 @@ func (h *Hosts) CheckAll(ip string, hosts ...string) map[string]bool {
 // Remove takes an ip and an optional host(s), if only an ip is passed the whole line is removed
 // when the optional hosts param is passed it will remove only those specific hosts from that ip
 func (h *Hosts) Remove(ip string, hosts ...string) error {
-	if net.ParseIP(ip) == nil {
-		return fmt.Errorf("%q is an invalid IP address", ip)
+	return h.apply(edit{op: opRemove, ip: ip, hosts: hosts})
+}
+
+func (h *Hosts) remove(ip string, hosts ...string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
 	}
 
 	if len(hosts) == 0 {
@@ -337,11 +601,11 @@ func (h *Hosts) Remove(ip string, hosts ...string) error {
 
 	lines := make([]HostsLine, len(h.Lines))
 	copy(lines, h.Lines)
-	h.Clear()
+	h.clear()
 
 	for _, line := range lines {
 		// add back all lines which were not the passed ip
-		if line.IP != ip {
+		if line.IP != ip || line.Disabled {
 			h.addLine(line)
 			continue
 		}
@@ -370,6 +634,10 @@ func (h *Hosts) Remove(ip string, hosts ...string) error {
 
 // RemoveByHostname go through all lines and remove a hostname if it exists
 func (h *Hosts) RemoveByHostname(host string) error {
+	return h.apply(edit{op: opRemoveByHostname, hosts: []string{host}})
+}
+
+func (h *Hosts) removeByHostname(host string) error {
 	restart := true
 	for restart {
 		restart = false
@@ -399,7 +667,12 @@ func (h *Hosts) RemoveByHostname(host string) error {
 	return nil
 }
 
+// RemoveByIP removes every line for the ip
 func (h *Hosts) RemoveByIP(ip string) {
+	h.applyQuiet(edit{op: opRemoveByIP, ip: ip})
+}
+
+func (h *Hosts) removeByIP(ip string) {
 	pos := h.ips.get(ip)
 	for _, p := range pos {
 		h.removeByPosition(p)
@@ -420,9 +693,13 @@ func (h *Hosts) RemoveDuplicateIps() {
 
 // CombineDuplicateIPs finds all duplicate ips and combines all their hosts into one line
 func (h *Hosts) CombineDuplicateIPs() {
+	h.applyQuiet(edit{op: opCombineDuplicateIPs})
+}
+
+func (h *Hosts) combineDuplicateIPs() {
 	ipCount := make(map[string]int)
 	for _, line := range h.Lines {
-		if line.IP == "" {
+		if line.IP == "" || line.Disabled {
 			continue // ignore comments
 		}
 		ipCount[line.IP]++
@@ -436,32 +713,49 @@ func (h *Hosts) CombineDuplicateIPs() {
 }
 
 func (h *Hosts) combineIP(ip string) {
-	newLine := HostsLine{
-		IP: ip,
-	}
+	// lines are only combined with lines that expire at the same time, so permanent hosts are never pruned and
+	// expiring hosts never become permanent
+	var newLines []HostsLine
+	group := make(map[string]int)
 
 	lines := make([]HostsLine, len(h.Lines))
 	copy(lines, h.Lines)
 
 	// clear the lines and position indexes to start over
-	h.Clear()
+	h.clear()
 	for _, line := range lines {
-		if line.IP == ip {
-			// if you find the ip combine it into newline
-			newLine.combine(line)
+		if line.IP == ip && !line.Disabled {
+			// if you find the ip combine it into the newline with the same expiry
+			var key string
+			if expires, ok := line.Expires(); ok {
+				key = expires.String()
+			}
+			i, ok := group[key]
+			if !ok {
+				i = len(newLines)
+				group[key] = i
+				newLines = append(newLines, HostsLine{IP: ip})
+			}
+			newLines[i].combine(line)
 			continue
 		}
 		// add everyone else
 		h.addLine(line)
 	}
 
-	// sort the hosts and add it to the end of Lines
-	newLine.SortHosts()
-	h.addLine(newLine)
+	// sort the hosts and add them to the end of Lines
+	for _, newLine := range newLines {
+		newLine.SortHosts()
+		h.addLine(newLine)
+	}
 }
 
 // RemoveDuplicateHosts will check each line and remove hosts if they are the same
 func (h *Hosts) RemoveDuplicateHosts() {
+	h.applyQuiet(edit{op: opRemoveDuplicateHosts})
+}
+
+func (h *Hosts) removeDuplicateHosts() {
 	for pos := range h.Lines {
 		if h.Lines[pos].IsComment() {
 			continue // skip comments
@@ -475,7 +769,14 @@ func (h *Hosts) RemoveDuplicateHosts() {
 
 // SortHosts will go through each line and sort the hosts in alpha order
 func (h *Hosts) SortHosts() {
+	h.applyQuiet(edit{op: opSortHosts})
+}
+
+func (h *Hosts) sortHosts() {
 	for pos := range h.Lines {
+		if h.Lines[pos].Disabled {
+			continue // commented out lines are kept as written
+		}
 		h.Lines[pos].SortHosts()
 	}
 }
@@ -487,10 +788,17 @@ func (h *Hosts) SortByIp() {
 
 // SortByIP convert to net.IP and byte.Compare, maintains all comment only lines at the top
 func (h *Hosts) SortIPs() {
+	h.applyQuiet(edit{op: opSortIPs})
+}
+
+func (h *Hosts) sortIPs() {
 	// create a new list of unique ips, if dupe ips they will still get grouped together
 	uniqueIPs := make([]net.IP, 0, len(h.Lines))
 	unique := make(map[string]struct{})
 	for _, l := range h.Lines {
+		if l.Disabled {
+			continue // kept with the comments
+		}
 		if _, ok := unique[l.IP]; !ok {
 			unique[l.IP] = struct{}{}
 			uniqueIPs = append(uniqueIPs, net.ParseIP(l.IP))
@@ -506,11 +814,11 @@ func (h *Hosts) SortIPs() {
 	lines := make([]HostsLine, len(h.Lines))
 	copy(lines, h.Lines)
 	// clear the lines and position indexes to start over
-	h.Clear()
+	h.clear()
 
 	// put all the comments back at the top
 	for _, l := range lines {
-		if l.IP == "" {
+		if l.IP == "" || l.Disabled {
 			h.addLine(l)
 		}
 	}
@@ -518,7 +826,7 @@ func (h *Hosts) SortIPs() {
 	// loop over the sorted ips and find their line and add it
 	for _, ip := range uniqueIPs {
 		for _, l := range lines {
-			if ip.String() == l.IP {
+			if ip.String() == l.IP && !l.Disabled {
 				h.addLine(l) // no continue to group duplicate ips
 			}
 		}
@@ -527,6 +835,10 @@ func (h *Hosts) SortIPs() {
 
 // HostsPerLine checks all ips and if their host count is greater than count will split into multiple lines with max of count hosts per line
 func (h *Hosts) HostsPerLine(count int) {
+	h.applyQuiet(edit{op: opHostsPerLine, count: count})
+}
+
+func (h *Hosts) hostsPerLine(count int) {
 	if count <= 0 {
 		return
 	}
@@ -536,9 +848,13 @@ func (h *Hosts) HostsPerLine(count int) {
 	copy(lines, h.Lines)
 
 	// clear the lines and position indexes to start over
-	h.Clear()
+	h.clear()
 
 	for ln, line := range lines {
+		if line.Disabled {
+			h.Lines = append(h.Lines, line)
+			continue
+		}
 		if len(line.Hosts) <= count {
 			for _, host := range line.Hosts {
 				h.hosts.add(host, ln)
@@ -584,7 +900,7 @@ func (h *Hosts) addLine(line HostsLine) {
 // removeByPosition will drop a line located at pos and reindex all lookups
 func (h *Hosts) removeByPosition(pos int) {
 	if pos == 0 && len(h.Lines) == 1 {
-		h.Clear()
+		h.clear()
 		return
 	}
 	h.Lines = append(h.Lines[:pos], h.Lines[pos+1:]...)
@@ -603,9 +919,77 @@ func (h *Hosts) reindex() {
 	h.ips.Unlock()
 
 	for pos, line := range h.Lines {
+		if line.Disabled {
+			continue
+		}
 		h.ips.add(line.IP, pos)
 		for _, host := range line.Hosts {
 			h.hosts.add(host, pos)
 		}
 	}
 }
+
+// lineEnding returns the line ending to write with
+func (h *Hosts) lineEnding() string {
+	if h.EOL == "" {
+		return h.profile().EOL
+	}
+	return h.EOL
+}
+
+// writeLines writes lines to w in the hosts file format, untouched lines are written exactly as they were read
+func (h *Hosts) writeLines(w io.Writer, lines []HostsLine) (int64, error) {
+	var total int64
+	if h.BOM {
+		n, err := w.Write(utf8BOM)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	end := h.lineEnding()
+	for i, line := range lines {
+		if h.missingEOL && i == len(lines)-1 {
+			end = ""
+		}
+		n, err := fmt.Fprintf(w, "%s%s", line.ToRaw(), end)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// trackingReader counts the bytes read through it, keeps the last one and detects the line ending from the first line
+type trackingReader struct {
+	r    io.Reader
+	n    int64
+	last byte
+	eol  string
+}
+
+func (t *trackingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if t.eol == "" {
+		prev := t.last
+		for _, b := range p[:n] {
+			if b == '\n' {
+				t.eol = "\n"
+				if prev == '\r' {
+					t.eol = "\r\n"
+				}
+				break
+			}
+			prev = b
+		}
+	}
+	if n > 0 {
+		t.n += int64(n)
+		t.last = p[n-1]
+	}
+	return n, err
+}
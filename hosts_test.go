@@ -1,6 +1,7 @@
 package hostsfile
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/icrowley/fake"
 	"github.com/stretchr/testify/assert"
@@ -551,6 +553,44 @@ func TestHosts_AddRaw(t *testing.T) {
 	assert.Error(t, hosts.AddRaw("127.0.0.1 host1%")) // fail host DNS validation
 }
 
+func TestHosts_AddRawFailsAtomically(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithHistory(5))
+	assert.Nil(t, err)
+
+	assert.Error(t, hosts.AddRaw("0.0.0.0 blocked.com", "10.0.0.1 bad%%% line"))
+	assert.False(t, hosts.HasHostname("blocked.com"))
+	assert.Equal(t, "127.0.0.1 localhost\n", hosts.String())
+	assert.False(t, hosts.CanUndo())
+
+	plan, err := hosts.Plan()
+	assert.Nil(t, err)
+	assert.Empty(t, plan.Operations)
+	assert.Empty(t, plan.Changes)
+}
+
+func TestHosts_ReplaceLine(t *testing.T) {
+	hosts := newHosts()
+	assert.Nil(t, hosts.AddRaw("# header", "127.0.0.1 yadda", "10.0.0.1 nada"))
+
+	assert.Nil(t, hosts.ReplaceLine(1, "127.0.0.2 yadda other"))
+	assert.Equal(t, "127.0.0.2 yadda other", hosts.Lines[1].Raw)
+	assert.False(t, hosts.HasIP("127.0.0.1"))
+	assert.True(t, hosts.HasAll("127.0.0.2", "yadda", "other"))
+
+	assert.Nil(t, hosts.ReplaceLine(2, "# nada is gone"))
+	assert.False(t, hosts.HasHostname("nada"))
+	assert.Nil(t, hosts.ReplaceLine(0, "10.0.0.2 header"))
+	assert.True(t, hosts.Has("10.0.0.2", "header"))
+	assert.Len(t, hosts.Lines, 3)
+
+	assert.Error(t, hosts.ReplaceLine(-1, "127.0.0.1 host1"))
+	assert.Error(t, hosts.ReplaceLine(3, "127.0.0.1 host1"))  // out of range
+	assert.Error(t, hosts.ReplaceLine(1, "badip host1"))      // fail ip parse
+	assert.Error(t, hosts.ReplaceLine(1, "127.0.0.1 host1%")) // fail host DNS validation
+	assert.Equal(t, "127.0.0.2 yadda other", hosts.Lines[1].Raw)
+}
+
 func TestHosts_HostsPerLine(t *testing.T) {
 	hosts := newHosts()
 	assert.Nil(t, hosts.Add("127.0.0.2", "host1", "host2", "host3", "host4", "host5", "host6", "host7", "host8", "host9", "hosts10"))
@@ -898,3 +938,96 @@ func TestHosts_BackupPath(t *testing.T) {
 	hosts := &Hosts{Path: "/etc/hosts"}
 	assert.Equal(t, "/etc/hosts.bak", hosts.BackupPath())
 }
+
+func TestNewHostsFromReader(t *testing.T) {
+	content := "\xef\xbb\xbf# comment" + eol + "127.0.0.1 localhost" + eol
+	hosts, err := NewHostsFromReader(strings.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, "", hosts.Path)
+	assert.Len(t, hosts.Lines, 2)
+	assert.True(t, hosts.Has("127.0.0.1", "localhost"))
+
+	n, err := hosts.ReadFrom(strings.NewReader("10.0.0.1 host1"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(14), n)
+	assert.Len(t, hosts.Lines, 1)
+	assert.False(t, hosts.HasHostname("localhost"))
+
+	_, err = NewHostsFromReader(iotest.ErrReader(errors.New("boom")))
+	assert.Error(t, err)
+}
+
+func TestHosts_WriteTo(t *testing.T) {
+	hosts := newTempHosts(t, "# comment\n127.0.0.1 localhost\n")
+	assert.Nil(t, hosts.Add("10.0.0.1", "host1"))
+
+	buf := new(bytes.Buffer)
+	n, err := hosts.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Nil(t, hosts.Flush())
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), buf.String())
+}
+
+func TestHosts_FlushRoundTrip(t *testing.T) {
+	content := strings.Join([]string{
+		"##",
+		"# Host Database",
+		"##",
+		"127.0.0.1\tlocalhost",
+		"255.255.255.255\tbroadcasthost   # broadcast",
+		"::1             localhost",
+		"",
+		"fe00::0 ",
+		"127.0.0.1\tscratch.test\t# MAMP PRO - Do NOT remove this entry!",
+	}, eol)
+
+	for _, c := range []string{content, content + eol} {
+		hosts := newTempHosts(t, c)
+		assert.Nil(t, hosts.Flush())
+		data, err := os.ReadFile(hosts.Path)
+		assert.Nil(t, err)
+		assert.Equal(t, c, string(data))
+	}
+
+	// only the changed line is regenerated
+	hosts := newTempHosts(t, content+eol)
+	assert.Nil(t, hosts.Add("127.0.0.1", "added.test"))
+	assert.Nil(t, hosts.Flush())
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Replace(content+eol, "scratch.test\t#", "scratch.test added.test\t#", 1), string(data))
+}
+
+func TestHosts_LineEndingsAndBOM(t *testing.T) {
+	content := "\xef\xbb\xbf# windows\r\n127.0.0.1 localhost\r\n"
+	hosts := newTempHosts(t, content)
+	assert.Equal(t, "\r\n", hosts.EOL)
+	assert.True(t, hosts.BOM)
+
+	assert.Nil(t, hosts.Flush())
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(data))
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "host1"))
+	assert.Nil(t, hosts.Flush())
+	data, err = os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, content+"10.0.0.1 host1\r\n", string(data))
+
+	// override per instance
+	hosts.EOL = "\n"
+	hosts.BOM = false
+	buf := new(bytes.Buffer)
+	_, err = hosts.WriteTo(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "# windows\n127.0.0.1 localhost\n10.0.0.1 host1\n", buf.String())
+
+	hosts = newTempHosts(t, "127.0.0.1 localhost\n")
+	assert.Equal(t, "\n", hosts.EOL)
+	assert.False(t, hosts.BOM)
+}
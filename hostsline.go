@@ -5,13 +5,17 @@ import (
 	"net"
 	"sort"
 	"strings"
+
+	"github.com/asaskevich/govalidator"
 )
 
 // HostsLine represents a line of the hosts file after being parsed into their respective parts
 type HostsLine struct {
-	IP      string   // IP found at the beginning of the line
-	Hosts   []string // Hosts split into a slice on the space char
-	Comment string   // Contents of everything after the comment char in the line
+	IP       string            // IP found at the beginning of the line
+	Hosts    []string          // Hosts split into a slice on the space char
+	Comment  string            // Contents of everything after the comment char in the line
+	Metadata map[string]string // "@key=value" annotations in Comment, nil when there are none, see SetMetadata
+	Disabled bool              // Line is a commented out entry e.g. "# 127.0.0.1 host", IP and Hosts are parsed from after the comment char
 
 	Raw string // Raw contents of the line as parsed in or updated after changes
 	Err error  // Used for error checking during parsing
@@ -30,6 +34,7 @@ func NewHostsLine(raw string) HostsLine {
 	}
 
 	if output.IsComment() { //whole line is comment
+		output.parseDisabled()
 		return output
 	}
 
@@ -45,6 +50,7 @@ func NewHostsLine(raw string) HostsLine {
 
 	output.IP = rawIP
 	output.Hosts = fields[1:]
+	output.Metadata = parseMetadata(output.Comment)
 
 	return output
 }
@@ -54,18 +60,163 @@ func (l *HostsLine) String() string {
 	return l.ToRaw()
 }
 
-// ToRaw returns the HostsLine's contents as a raw string
+// ToRaw returns the HostsLine's contents as a raw string. When IP, Hosts and Comment still match Raw it's returned
+// untouched, otherwise the line is regenerated keeping the whitespace layout of Raw.
 func (l *HostsLine) ToRaw() string {
-	var comment string
+	if l.Disabled {
+		prefix, body := splitDisabled(l.Raw)
+		if prefix == "" {
+			prefix = commentChar + " "
+		}
+		entry := HostsLine{IP: l.IP, Hosts: l.Hosts, Comment: l.entryComment(), Metadata: l.Metadata, Raw: body}
+		return prefix + entry.ToRaw()
+	}
 	if l.IsComment() { //Whole line is comment
 		return l.Raw
 	}
 
-	if l.Comment != "" {
-		comment = fmt.Sprintf(" %s%s", commentChar, l.Comment)
+	lineComment := l.metadataComment()
+	fields, comment := splitRaw(l.Raw)
+	if comment == lineComment && len(fields) > 0 && fields[0] == l.IP && equalStrings(fields[1:], l.Hosts) {
+		return l.Raw
+	}
+	if comment == lineComment && len(fields) == 0 && l.IP == "" && len(l.Hosts) == 0 {
+		return l.Raw // blank line
+	}
+
+	layout := newLineLayout(l.Raw)
+	var b strings.Builder
+	b.WriteString(layout.indent)
+	b.WriteString(l.IP)
+	if len(l.Hosts) > 0 {
+		b.WriteString(layout.ipSep)
+		b.WriteString(strings.Join(l.Hosts, layout.hostSep))
+	}
+	if lineComment != "" {
+		if b.Len() > 0 {
+			b.WriteString(layout.commentSep)
+		}
+		b.WriteString(commentChar)
+		b.WriteString(lineComment)
+	}
+	return b.String()
+}
+
+// splitRaw splits a raw line into the fields before the comment char and the comment after it
+func splitRaw(raw string) ([]string, string) {
+	idx := strings.Index(raw, commentChar)
+	if idx < 0 {
+		return strings.Fields(raw), ""
+	}
+	return strings.Fields(raw[:idx]), raw[idx+1:]
+}
+
+// parseDisabled sets IP, Hosts and Metadata of a comment line when what follows the comment char is an entry, Comment
+// is left as everything after the comment char the same as any other comment line. To keep regular comments and the
+// examples in documentation comments (e.g. the default Windows hosts file) from being picked up the comment char can
+// only be followed by a single space, the first field has to be an ip and every host a valid hostname.
+func (l *HostsLine) parseDisabled() {
+	prefix, body := splitDisabled(l.Raw)
+	if prefix == "" || strings.TrimLeft(body, " \t") != body {
+		return
+	}
+	fields, comment := splitRaw(body)
+	if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+		return
+	}
+	for _, host := range fields[1:] {
+		if !govalidator.IsDNSName(host) {
+			return
+		}
+	}
+	l.Disabled = true
+	l.IP = fields[0]
+	l.Hosts = fields[1:]
+	l.Metadata = parseMetadata(comment)
+}
+
+// splitDisabled splits a commented out line into the comment char with the space after it and the entry after that
+func splitDisabled(raw string) (string, string) {
+	idx := strings.Index(raw, commentChar)
+	if idx < 0 || strings.TrimSpace(raw[:idx]) != "" {
+		return "", raw
+	}
+	body := strings.TrimPrefix(raw[idx+1:], " ")
+	return raw[:len(raw)-len(body)], body
+}
+
+// entryComment returns the trailing comment of the entry, for disabled lines that's the comment after the entry
+func (l *HostsLine) entryComment() string {
+	if !l.Disabled {
+		return l.Comment
+	}
+	_, comment := splitRaw(strings.TrimLeft(l.Comment, " \t"))
+	return comment
+}
+
+// disable comments out the line keeping its layout
+func (l *HostsLine) disable() {
+	if l.Disabled || l.IsComment() {
+		return
 	}
+	raw := l.ToRaw()
+	l.Raw = commentChar + " " + strings.TrimLeft(raw, " \t")
+	l.Comment = l.Raw[len(commentChar):]
+	l.Disabled = true
+}
 
-	return fmt.Sprintf("%s %s%s", l.IP, strings.Join(l.Hosts, " "), comment)
+// enable uncomments a disabled line keeping its layout
+func (l *HostsLine) enable() {
+	if !l.Disabled {
+		return
+	}
+	_, body := splitDisabled(l.ToRaw())
+	l.Comment = l.metadataComment()
+	l.Raw = body
+	l.Disabled = false
+}
+
+// lineLayout is the whitespace around the fields of a raw line, used to regenerate a changed line in the same style
+type lineLayout struct {
+	indent     string // before the ip
+	ipSep      string // between the ip and the first host
+	hostSep    string // between hosts
+	commentSep string // between the last host and the comment char
+}
+
+func newLineLayout(raw string) lineLayout {
+	layout := lineLayout{ipSep: " ", hostSep: " ", commentSep: " "}
+
+	body := raw
+	if idx := strings.Index(raw, commentChar); idx >= 0 {
+		body = raw[:idx]
+		if trimmed := strings.TrimRight(body, " \t"); trimmed != "" && len(trimmed) < len(body) {
+			layout.commentSep = body[len(trimmed):]
+		}
+	}
+
+	// collect the whitespace between each of the fields
+	fields := strings.TrimLeft(body, " \t")
+	layout.indent = body[:len(body)-len(fields)]
+	fields = strings.TrimRight(fields, " \t")
+	var seps []string
+	for {
+		idx := strings.IndexAny(fields, " \t")
+		if idx < 0 {
+			break
+		}
+		next := strings.TrimLeft(fields[idx:], " \t")
+		seps = append(seps, fields[idx:len(fields)-len(next)])
+		fields = next
+	}
+
+	if len(seps) > 0 {
+		layout.ipSep = seps[0]
+	}
+	if len(seps) > 1 {
+		layout.hostSep = seps[1]
+	}
+	return layout
 }
 
 // RemoveDuplicateHosts checks all hosts in a line and removes duplicates
@@ -91,11 +242,37 @@ func (l *HostsLine) Combine(hostline HostsLine) {
 }
 
 func (l *HostsLine) combine(hostline HostsLine) {
+	_, expires := l.Expires()
+	permanent := len(l.Hosts) > 0 && !expires
 	l.Hosts = append(l.Hosts, hostline.Hosts...)
+	l.Comment = l.metadataComment()
+
+	// a permanent line never takes the expiry of the line combined into it
+	other := hostline
+	other.Metadata = parseMetadata(hostline.metadataComment())
+	if permanent {
+		delete(other.Metadata, expiresKey)
+	}
 	if l.Comment == "" {
-		l.Comment = hostline.Comment
+		if comment := other.metadataComment(); strings.TrimSpace(comment) != "" {
+			l.Comment = comment
+		}
 	} else {
-		l.Comment = fmt.Sprintf("%s %s", l.Comment, hostline.Comment)
+		// annotations already on l win, drop them from hostline so the keys aren't repeated
+		for key := range parseMetadata(l.Comment) {
+			delete(other.Metadata, key)
+		}
+		if comment := other.metadataComment(); strings.TrimSpace(comment) != "" {
+			l.Comment = fmt.Sprintf("%s %s", l.Comment, comment)
+		}
+	}
+	l.Metadata = parseMetadata(l.Comment)
+
+	// combined expiring lines expire with the earliest of them, combining with a permanent line keeps it permanent
+	if !permanent {
+		theirs := hostline
+		theirs.Metadata = parseMetadata(hostline.metadataComment())
+		l.earliestExpiry(theirs)
 	}
 	l.RegenRaw()
 }
@@ -113,8 +290,9 @@ func (l *HostsLine) HasComment() bool {
 	return strings.Contains(l.Raw, commentChar)
 }
 
+// IsValid returns true for entry lines, disabled entries are comments so they aren't valid
 func (l *HostsLine) IsValid() bool {
-	return l.IP != ""
+	return l.IP != "" && !l.Disabled
 }
 
 func (l *HostsLine) IsMalformed() bool {
@@ -123,4 +301,9 @@ func (l *HostsLine) IsMalformed() bool {
 
 func (l *HostsLine) RegenRaw() {
 	l.Raw = l.ToRaw()
+	if l.Disabled {
+		l.Comment = l.Raw[strings.Index(l.Raw, commentChar)+len(commentChar):]
+	} else if !l.IsComment() {
+		l.Comment = l.metadataComment()
+	}
 }
@@ -44,3 +44,26 @@ func TestHostsline_CommentWithMultipleHashes(t *testing.T) {
 	assert.Equal(t, " first # second # third", hl2.Comment)
 	assert.Equal(t, raw2, hl2.ToRaw())
 }
+
+func TestHostsline_ToRawKeepsLayout(t *testing.T) {
+	raw := "  127.0.0.1\tlocalhost   local\t# loopback"
+	hl := NewHostsLine(raw)
+	assert.Equal(t, raw, hl.ToRaw())
+
+	// changed lines keep the whitespace style of the original
+	hl.Hosts = append(hl.Hosts, "added")
+	assert.Equal(t, "  127.0.0.1\tlocalhost   local   added\t# loopback", hl.ToRaw())
+
+	hl.Comment = ""
+	assert.Equal(t, "  127.0.0.1\tlocalhost   local   added", hl.ToRaw())
+
+	hl = NewHostsLine("127.0.0.1\tlocalhost")
+	hl.Comment = " new"
+	assert.Equal(t, "127.0.0.1\tlocalhost # new", hl.ToRaw())
+
+	// ip only and blank lines come back untouched
+	for _, raw := range []string{"fe00::0 ", "", "   "} {
+		hl = NewHostsLine(raw)
+		assert.Equal(t, raw, hl.ToRaw())
+	}
+}
@@ -0,0 +1,208 @@
+package hostsfile
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// Severity is how serious a Diagnostic found by Lint is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	SeverityOff     Severity = "off" // disables the rule, see WithLintRule
+)
+
+// LintRule identifies a check made by Lint
+type LintRule string
+
+const (
+	RuleMalformedLine      LintRule = "malformed-line"      // the line doesn't start with a valid ip
+	RuleInvalidHostname    LintRule = "invalid-hostname"    // a host isn't a valid dns name
+	RuleIPOnly             LintRule = "ip-only"             // the line has an ip but no hosts
+	RuleDuplicateHost      LintRule = "duplicate-host"      // the same ip/host combo appears more than once
+	RuleConflictingMapping LintRule = "conflicting-mapping" // a host is mapped to different ips of the same family
+)
+
+type lintSeverities map[LintRule]Severity
+
+// defaultSeverities is the severity of each rule unless changed with WithLintRule
+var defaultSeverities = lintSeverities{
+	RuleMalformedLine:      SeverityError,
+	RuleInvalidHostname:    SeverityError,
+	RuleIPOnly:             SeverityWarning,
+	RuleDuplicateHost:      SeverityWarning,
+	RuleConflictingMapping: SeverityError,
+}
+
+// Diagnostic is a problem found by Lint, line and column start at 1
+type Diagnostic struct {
+	Path     string   `json:"path,omitempty"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Rule     LintRule `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// String to make Diagnostic a fmt.Stringer e.g. "/etc/hosts:3:11: error: invalid-hostname: ..."
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s: %s", d.Path, d.Line, d.Column, d.Severity, d.Rule, d.Message)
+}
+
+// Diagnostics is the result of Lint, sorted by line and column
+type Diagnostics []Diagnostic
+
+// HasErrors returns true if any of the diagnostics is an error
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns one diagnostic per line
+func (d Diagnostics) String() string {
+	var b strings.Builder
+	for _, diagnostic := range d {
+		b.WriteString(diagnostic.String())
+		b.WriteString(eol)
+	}
+	return b.String()
+}
+
+// WithLintRule sets the severity Lint reports rule with, SeverityOff disables it
+func WithLintRule(rule LintRule, severity Severity) Option {
+	return func(h *Hosts) {
+		if h.config.lintRules == nil {
+			h.config.lintRules = make(lintSeverities)
+		}
+		h.config.lintRules[rule] = severity
+	}
+}
+
+// Lint checks the entry lines for problems that parsing lets through, comments and disabled entries are skipped. The
+// diagnostics can be encoded to JSON for tooling.
+func (h *Hosts) Lint() Diagnostics {
+	defer h.lockRead()()
+	l := linter{h: h, mappings: make(map[string]lintMapping), entries: make(map[string]int)}
+	for pos, line := range h.Lines {
+		if line.IsComment() || line.IP == "" {
+			continue
+		}
+		l.line(pos+1, line)
+	}
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diagnostics
+}
+
+// lintMapping is where a host was first mapped to an ip of a family
+type lintMapping struct {
+	ip   string
+	line int
+}
+
+type linter struct {
+	h           *Hosts
+	mappings    map[string]lintMapping // family and host to the first mapping seen
+	entries     map[string]int         // ip and host to the first line they were seen on
+	diagnostics Diagnostics
+}
+
+func (l *linter) line(n int, line HostsLine) {
+	columns := fieldColumns(line.ToRaw())
+	parsed := net.ParseIP(line.IP)
+	if line.Err != nil || parsed == nil {
+		l.report(n, fieldColumn(columns, 0), RuleMalformedLine, "%q is not a valid ip address", line.IP)
+		return
+	}
+	if len(line.Hosts) == 0 {
+		l.report(n, fieldColumn(columns, 0), RuleIPOnly, "%s has no hosts", line.IP)
+		return
+	}
+
+	family := "ipv6"
+	if parsed.To4() != nil {
+		family = "ipv4"
+	}
+	for i, host := range line.Hosts {
+		column := fieldColumn(columns, i+1)
+		if !govalidator.IsDNSName(host) {
+			l.report(n, column, RuleInvalidHostname, "%q is not a valid hostname", host)
+			continue
+		}
+
+		entry := line.IP + " " + host
+		if first, ok := l.entries[entry]; ok {
+			l.report(n, column, RuleDuplicateHost, "%s is already mapped to %s on line %d", host, line.IP, first)
+			continue
+		}
+		l.entries[entry] = n
+
+		key := family + " " + host
+		if first, ok := l.mappings[key]; !ok {
+			l.mappings[key] = lintMapping{ip: line.IP, line: n}
+		} else if first.ip != line.IP {
+			l.report(n, column, RuleConflictingMapping, "%s is mapped to %s here but to %s on line %d", host, line.IP, first.ip, first.line)
+		}
+	}
+}
+
+func (l *linter) report(line, column int, rule LintRule, format string, args ...interface{}) {
+	severity, ok := l.h.config.lintRules[rule]
+	if !ok {
+		severity = defaultSeverities[rule]
+	}
+	if severity == SeverityOff {
+		return
+	}
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Path:     l.h.Path,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// fieldColumns returns the column each field before the comment char starts at, split the same as NewHostsLine
+func fieldColumns(raw string) []int {
+	if idx := strings.Index(raw, commentChar); idx >= 0 {
+		raw = raw[:idx]
+	}
+	var columns []int
+	inField := false
+	for i, r := range raw {
+		space := unicode.IsSpace(r)
+		if !space && !inField {
+			columns = append(columns, i+1)
+		}
+		inField = !space
+	}
+	return columns
+}
+
+// fieldColumn returns the column of field i, 1 when the line doesn't have it e.g. Lines edited without RegenRaw
+func fieldColumn(columns []int, i int) int {
+	if i < len(columns) {
+		return columns[i]
+	}
+	return 1
+}
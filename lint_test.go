@@ -0,0 +1,80 @@
+package hostsfile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_Lint(t *testing.T) {
+	hosts := loadHosts(t,
+		"# comment with 10.0.0.1 bad!host",
+		"127.0.0.1 localhost",
+		"::1 localhost",
+		"10.0.0.1 app bad!host",
+		"  10.0.0.2",
+		"not-an-ip app",
+		"10.0.0.3\tapp   # app conflicts with line 4",
+		"10.0.0.1 db app",
+		"# 10.0.0.9 app",
+	)
+	hosts.Path = "hosts"
+
+	diagnostics := hosts.Lint()
+	assert.Equal(t, Diagnostics{
+		{Path: "hosts", Line: 4, Column: 14, Severity: SeverityError, Rule: RuleInvalidHostname, Message: `"bad!host" is not a valid hostname`},
+		{Path: "hosts", Line: 5, Column: 3, Severity: SeverityWarning, Rule: RuleIPOnly, Message: "10.0.0.2 has no hosts"},
+		{Path: "hosts", Line: 6, Column: 1, Severity: SeverityError, Rule: RuleMalformedLine, Message: `"not-an-ip" is not a valid ip address`},
+		{Path: "hosts", Line: 7, Column: 10, Severity: SeverityError, Rule: RuleConflictingMapping, Message: "app is mapped to 10.0.0.3 here but to 10.0.0.1 on line 4"},
+		{Path: "hosts", Line: 8, Column: 13, Severity: SeverityWarning, Rule: RuleDuplicateHost, Message: "app is already mapped to 10.0.0.1 on line 4"},
+	}, diagnostics)
+	assert.True(t, diagnostics.HasErrors())
+	assert.Equal(t, "hosts:4:14: error: invalid-hostname: \"bad!host\" is not a valid hostname\n", diagnostics[:1].String())
+
+	out, err := json.Marshal(diagnostics[1])
+	assert.Nil(t, err)
+	assert.Equal(t, `{"path":"hosts","line":5,"column":3,"severity":"warning","rule":"ip-only","message":"10.0.0.2 has no hosts"}`, string(out))
+
+	assert.Empty(t, loadHosts(t, "127.0.0.1 localhost", "::1 localhost ip6-localhost").Lint())
+}
+
+func TestHosts_LintRules(t *testing.T) {
+	hosts := loadHosts(t,
+		"10.0.0.1 app",
+		"10.0.0.2 app",
+		"10.0.0.3",
+	)
+	WithLintRule(RuleConflictingMapping, SeverityWarning)(hosts)
+	WithLintRule(RuleIPOnly, SeverityOff)(hosts)
+
+	diagnostics := hosts.Lint()
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, RuleConflictingMapping, diagnostics[0].Rule)
+	assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+	assert.False(t, diagnostics.HasErrors())
+}
+
+func TestHosts_LintOddLines(t *testing.T) {
+	// vertical tab splits fields the same as it does when parsing
+	hosts := loadHosts(t, "127.0.0.1 a\vb!")
+	assert.Equal(t, Diagnostics{
+		{Line: 1, Column: 13, Severity: SeverityError, Rule: RuleInvalidHostname, Message: `"b!" is not a valid hostname`},
+	}, hosts.Lint())
+
+	// lines added to Lines directly without a Raw
+	hosts = loadHosts(t, "127.0.0.1 localhost")
+	hosts.Lines = append(hosts.Lines, HostsLine{IP: "10.0.0.1", Hosts: []string{"bad!"}}, HostsLine{IP: "10.0.0.2", Raw: ""})
+	assert.Equal(t, Diagnostics{
+		{Line: 2, Column: 10, Severity: SeverityError, Rule: RuleInvalidHostname, Message: `"bad!" is not a valid hostname`},
+		{Line: 3, Column: 1, Severity: SeverityWarning, Rule: RuleIPOnly, Message: "10.0.0.2 has no hosts"},
+	}, hosts.Lint())
+	assert.Equal(t, 1, fieldColumn(nil, 1))
+}
+
+func TestFieldColumns(t *testing.T) {
+	assert.Equal(t, []int{1, 10, 15}, fieldColumns("10.0.0.1 app\t\tdb # x y"))
+	assert.Equal(t, []int{3}, fieldColumns("  ::1"))
+	assert.Nil(t, fieldColumns("# comment"))
+	assert.Equal(t, []int{1, 11, 13}, fieldColumns("127.0.0.1 a\vb"))
+}
@@ -0,0 +1,126 @@
+package hostsfile
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrAlreadyLocked is returned when calling Lock on a Hosts that already holds the lock
+	ErrAlreadyLocked = errors.New("hosts file is already locked")
+	// ErrNotLocked is returned when calling Unlock on a Hosts that doesn't hold the lock
+	ErrNotLocked = errors.New("hosts file is not locked")
+)
+
+// LockPath returns the path of the lock file used to coordinate access to the hosts file. A separate file is used
+// because Flush replaces the hosts file on every write which would drop any lock held on the file itself.
+func (h *Hosts) LockPath() string {
+	return h.Path + ".lock"
+}
+
+// Lock acquires an exclusive advisory lock on the hosts file, blocking until any other process (or Hosts) holding it
+// calls Unlock. The lock only coordinates programs using it, it does not stop anything else from writing the file.
+// Storages other than DiskStorage and MemoryStorage are only locked against other Hosts in the same process.
+func (h *Hosts) Lock() error {
+	if h.isLocked() {
+		return ErrAlreadyLocked
+	}
+
+	// wait for the lock without blocking everyone else using the Hosts
+	unlock, err := h.lockStorage()
+	if err != nil {
+		return err
+	}
+
+	defer h.lockWrite()()
+	if h.lock != nil {
+		_ = unlock()
+		return ErrAlreadyLocked
+	}
+	h.lock = unlock
+	return nil
+}
+
+// lockStorage locks LockPath through the Storage, falling back to a lock held in this process
+func (h *Hosts) lockStorage() (func() error, error) {
+	if storage, ok := h.Storage().(lockingStorage); ok {
+		return storage.Lock(h.LockPath())
+	}
+	return defaultLocks.lock(h.LockPath()), nil
+}
+
+func (h *Hosts) isLocked() bool {
+	defer h.lockRead()()
+	return h.lock != nil
+}
+
+// Unlock releases the lock acquired by Lock
+func (h *Hosts) Unlock() error {
+	defer h.lockWrite()()
+	if h.lock == nil {
+		return ErrNotLocked
+	}
+
+	unlock := h.lock
+	h.lock = nil
+	return unlock()
+}
+
+// Update locks the hosts file, reloads it from disk, applies fn and flushes the result before releasing the lock. If fn
+// returns an error nothing is written and the error is returned.
+func (h *Hosts) Update(fn func(*Hosts) error) (err error) {
+	if err := h.Lock(); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := h.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if err := h.Load(); err != nil {
+		return err
+	}
+
+	if err := fn(h); err != nil {
+		return err
+	}
+
+	return h.Flush()
+}
+
+// defaultLocks are the locks for storages that can't lock their files themselves
+var defaultLocks processLocks
+
+// processLocks are exclusive locks by path held within the process
+type processLocks struct {
+	mu   sync.Mutex
+	held map[string]chan struct{} // closed when the lock is released
+}
+
+// lock blocks until path is unlocked and returns the func releasing it
+func (p *processLocks) lock(path string) func() error {
+	for {
+		p.mu.Lock()
+		released, held := p.held[path]
+		if !held {
+			break
+		}
+		p.mu.Unlock()
+		<-released
+	}
+	defer p.mu.Unlock()
+
+	if p.held == nil {
+		p.held = make(map[string]chan struct{})
+	}
+	released := make(chan struct{})
+	p.held[path] = released
+	return func() error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.held, path)
+		close(released)
+		return nil
+	}
+}
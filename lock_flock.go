@@ -0,0 +1,44 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package hostsfile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fileLock holds an flock on the lock file until unlock is called
+type fileLock struct {
+	f *os.File
+}
+
+func lockFile(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &fileLock{f: f}, nil
+}
+
+func (l *fileLock) unlock() error {
+	// the lock file is left in place, removing it would let another process lock a new file while one is waiting on the old
+	if err := syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN); err != nil {
+		_ = l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,105 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package hostsfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	lockRetryInterval = 50 * time.Millisecond
+	// staleLockAge is how old a lock file can get before it's assumed its owner died without cleaning up
+	staleLockAge = time.Minute
+	// lockRefreshInterval is how often the holder touches the lock file so it never looks stale while held
+	lockRefreshInterval = staleLockAge / 4
+)
+
+// fileLock is a lock file created exclusively, used where flock isn't available
+type fileLock struct {
+	path string
+	f    *os.File
+	stop chan struct{} // closed by unlock to stop refreshing the lock file
+	done chan struct{} // closed once refreshing has stopped
+}
+
+func lockFile(path string) (*fileLock, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = fmt.Fprintf(f, "%d", os.Getpid())
+			l := &fileLock{path: path, f: f, stop: make(chan struct{}), done: make(chan struct{})}
+			go l.refresh(lockRefreshInterval)
+			return l, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			removeStale(path)
+			continue
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// removeStale moves a stale lock file out of the way. Waiters that saw the same stale file race to rename it and only
+// one rename succeeds, the others would rename the fresh lock the winner created so that's checked and put back.
+func removeStale(path string) {
+	stale := fmt.Sprintf("%s.stale.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, stale); err != nil {
+		return
+	}
+	if info, err := os.Stat(stale); err == nil && time.Since(info.ModTime()) <= staleLockAge {
+		_ = os.Link(stale, path) // fails if yet another waiter already took the free lock
+	}
+	_ = os.Remove(stale)
+}
+
+// refresh updates the modification time of the lock file until unlock so a long held lock isn't taken as stale
+func (l *fileLock) refresh(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			_ = os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+func (l *fileLock) unlock() error {
+	close(l.stop)
+	<-l.done
+	owned := l.owned()
+	if err := l.f.Close(); err != nil {
+		if owned {
+			_ = os.Remove(l.path)
+		}
+		return err
+	}
+	if !owned {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// owned reports if the file at path is still the lock file created by lockFile and not one a waiter made after taking
+// it over
+func (l *fileLock) owned() bool {
+	held, err := l.f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(l.path)
+	return err == nil && os.SameFile(held, current)
+}
@@ -0,0 +1,67 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package hostsfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLock_Refresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.lock")
+	l, err := lockFile(path)
+	assert.Nil(t, err)
+
+	// stop the regular refresh and check a short one keeps the lock file fresh
+	close(l.stop)
+	<-l.done
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+	old := time.Now().Add(-2 * staleLockAge)
+	assert.Nil(t, os.Chtimes(path, old, old))
+	go l.refresh(10 * time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		info, err := os.Stat(path)
+		return err == nil && time.Since(info.ModTime()) < staleLockAge
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, l.unlock())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileLock_StaleTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.lock")
+	assert.Nil(t, os.WriteFile(path, []byte("1"), 0644))
+	old := time.Now().Add(-2 * staleLockAge)
+	assert.Nil(t, os.Chtimes(path, old, old))
+
+	// every waiter saw the stale lock, only the first one to take it over gets it
+	removeStale(path)
+	first, err := lockFile(path)
+	assert.Nil(t, err)
+	removeStale(path)
+	assert.True(t, first.owned())
+
+	held := make(chan *fileLock)
+	go func() {
+		l, _ := lockFile(path)
+		held <- l
+	}()
+	select {
+	case <-held:
+		t.Fatal("took a lock that was still held")
+	case <-time.After(5 * lockRetryInterval):
+	}
+	assert.Nil(t, first.unlock())
+	second := <-held
+	assert.Nil(t, second.unlock())
+
+	matches, _ := filepath.Glob(path + "*")
+	assert.Empty(t, matches)
+}
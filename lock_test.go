@@ -0,0 +1,148 @@
+package hostsfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTempHosts(t *testing.T, content string) *Hosts {
+	fp := filepath.Join(t.TempDir(), "hosts")
+	assert.Nil(t, os.WriteFile(fp, []byte(content), 0644))
+	hosts, err := NewCustomHosts(fp)
+	assert.Nil(t, err)
+	return hosts
+}
+
+func TestHosts_Lock(t *testing.T) {
+	a := newTempHosts(t, "")
+	b, err := NewCustomHosts(a.Path)
+	assert.Nil(t, err)
+
+	assert.ErrorIs(t, a.Unlock(), ErrNotLocked)
+	assert.Nil(t, a.Lock())
+	assert.ErrorIs(t, a.Lock(), ErrAlreadyLocked)
+
+	locked := make(chan struct{})
+	go func() {
+		assert.Nil(t, b.Lock())
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("second lock acquired while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Nil(t, a.Unlock())
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock never acquired")
+	}
+	assert.Nil(t, b.Unlock())
+}
+
+func TestHosts_Update(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n")
+
+	assert.Nil(t, hosts.Update(func(h *Hosts) error {
+		return h.Add("10.0.0.1", "host1")
+	}))
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 localhost"+eol+"10.0.0.1 host1"+eol, string(data))
+
+	// errors from the callback skip the flush
+	boom := errors.New("boom")
+	assert.ErrorIs(t, hosts.Update(func(h *Hosts) error {
+		assert.Nil(t, h.Add("10.0.0.2", "host2"))
+		return boom
+	}), boom)
+	data, err = os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "host2")
+
+	// lock is released after an error
+	assert.Nil(t, hosts.Lock())
+	assert.Nil(t, hosts.Unlock())
+}
+
+func TestHosts_UpdateConcurrent(t *testing.T) {
+	fp := newTempHosts(t, "").Path
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hosts, err := NewCustomHosts(fp)
+			assert.Nil(t, err)
+			assert.Nil(t, hosts.Update(func(h *Hosts) error {
+				return h.Add(fmt.Sprintf("10.0.0.%d", i+1), fmt.Sprintf("host%d", i))
+			}))
+		}(i)
+	}
+	wg.Wait()
+
+	hosts, err := NewCustomHosts(fp)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		assert.True(t, hosts.Has(fmt.Sprintf("10.0.0.%d", i+1), fmt.Sprintf("host%d", i)))
+	}
+}
+
+func TestHosts_UpdateMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"/nonexistent-dir/hosts": []byte("127.0.0.1 localhost\n")})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hosts, err := NewHostsWithStorage("/nonexistent-dir/hosts", storage)
+			assert.Nil(t, err)
+			assert.Nil(t, hosts.Update(func(h *Hosts) error {
+				return h.Add(fmt.Sprintf("10.0.0.%d", i+1), fmt.Sprintf("host%d", i))
+			}))
+		}(i)
+	}
+	wg.Wait()
+
+	hosts, err := NewHostsWithStorage("/nonexistent-dir/hosts", storage)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		assert.True(t, hosts.Has(fmt.Sprintf("10.0.0.%d", i+1), fmt.Sprintf("host%d", i)))
+	}
+	_, err = os.Stat("/nonexistent-dir/hosts.lock")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestProcessLocks(t *testing.T) {
+	var locks processLocks
+	unlock := locks.lock("a")
+	unlockB := locks.lock("b") // other paths aren't blocked
+
+	locked := make(chan struct{})
+	go func() {
+		assert.Nil(t, locks.lock("a")())
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second lock acquired while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Nil(t, unlock())
+	<-locked
+	assert.Nil(t, unlockB())
+	assert.Empty(t, locks.held)
+}
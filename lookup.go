@@ -40,3 +40,17 @@ func (lo *lookup) reset() {
 	defer lo.Unlock()
 	lo.l = make(map[string][]int)
 }
+
+// copy returns a deep copy of the positions
+func (lo *lookup) copy() map[string][]int {
+	lo.RLock()
+	defer lo.RUnlock()
+	return copyPositions(lo.l)
+}
+
+// replace swaps the positions for l
+func (lo *lookup) replace(l map[string][]int) {
+	lo.Lock()
+	defer lo.Unlock()
+	lo.l = l
+}
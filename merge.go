@@ -0,0 +1,299 @@
+package hostsfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeConflict is a hostname that both sides of a Merge3 changed in different ways, e.g. pointed it at different ips
+type MergeConflict struct {
+	Host   string
+	Base   []string // ips the host had in base
+	Ours   []string // ips the host has in ours, these are used in the merged result
+	Theirs []string // ips the host has in theirs
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s: base [%s] ours [%s] theirs [%s]", c.Host,
+		strings.Join(c.Base, " "), strings.Join(c.Ours, " "), strings.Join(c.Theirs, " "))
+}
+
+// MergeError is returned by Flush with ConflictMerge when the changes on disk can't be merged automatically
+type MergeError struct {
+	Conflicts []MergeConflict
+}
+
+func (e *MergeError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		conflicts = append(conflicts, c.String())
+	}
+	return fmt.Sprintf("%s, merge conflicts: %s", ErrConcurrentModification, strings.Join(conflicts, ", "))
+}
+
+// Is makes a MergeError match ErrConcurrentModification with errors.Is
+func (e *MergeError) Is(target error) bool {
+	return target == ErrConcurrentModification
+}
+
+type entry struct {
+	ip   string
+	host string
+}
+
+// Merge3 does a three way merge of the changes made in ours and theirs since base. Entries (ip and hostname pairs)
+// added or removed by either side are added or removed in the result, comment and blank lines are merged the same way
+// by their contents. The layout of theirs is kept and anything new from ours is placed after the line it followed in
+// ours. A hostname whose ips were changed differently by both sides is reported as a conflict and resolved using ours.
+func Merge3(base, ours, theirs *Hosts) (*Hosts, []MergeConflict) {
+	return merge3(snapshotHosts(base), snapshotHosts(ours), snapshotHosts(theirs))
+}
+
+// snapshotHosts copies h under its read lock so it can be merged while others edit it
+func snapshotHosts(h *Hosts) *Hosts {
+	path, lines := h.linesCopy()
+	return newHostsFromLines(path, lines)
+}
+
+func merge3(base, ours, theirs *Hosts) (*Hosts, []MergeConflict) {
+	merged, conflicts := mergeEntries(base, ours, theirs)
+
+	// start from the layout of theirs keeping only the entries that survived the merge
+	placed := make(map[entry]struct{})
+	lines := make([]HostsLine, 0, len(theirs.Lines))
+	for _, line := range theirs.Lines {
+		if !isEntryLine(line) {
+			lines = append(lines, line)
+			continue
+		}
+
+		var hosts []string
+		for _, host := range line.Hosts {
+			e := entry{line.IP, host}
+			if _, ok := merged[e]; ok {
+				hosts = append(hosts, host)
+				placed[e] = struct{}{}
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		if len(hosts) != len(line.Hosts) {
+			line.Hosts = hosts
+			line.RegenRaw()
+		}
+		lines = append(lines, line)
+	}
+
+	lines = removeLayoutLines(lines, layoutChanges(base, ours), layoutChanges(base, theirs))
+
+	// walk ours in order adding whatever is missing after the last line both sides share
+	added := layoutChanges(base, ours)
+	theirsAdded := layoutChanges(base, theirs)
+	anchor := -1
+	for _, line := range ours.Lines {
+		if !isEntryLine(line) {
+			if added[line.Raw] > 0 && theirsAdded[line.Raw] > 0 {
+				added[line.Raw]-- // both added it
+				theirsAdded[line.Raw]--
+			} else if added[line.Raw] > 0 {
+				added[line.Raw]--
+				lines = insertLine(lines, anchor+1, line)
+				anchor++
+				continue
+			}
+			if pos := findLine(lines, anchor+1, func(l HostsLine) bool { return l.Raw == line.Raw }); pos >= 0 {
+				anchor = pos
+			}
+			continue
+		}
+
+		var missing []string
+		for _, host := range line.Hosts {
+			e := entry{line.IP, host}
+			if _, ok := merged[e]; !ok {
+				continue
+			}
+			if _, ok := placed[e]; !ok {
+				missing = append(missing, host)
+				placed[e] = struct{}{}
+			}
+		}
+
+		pos := findLine(lines, anchor+1, func(l HostsLine) bool { return sameEntryLine(l, line) })
+		if pos < 0 {
+			if len(missing) > 0 {
+				newLine := line
+				if len(missing) != len(line.Hosts) {
+					newLine.Hosts = missing
+					newLine.RegenRaw()
+				}
+				lines = insertLine(lines, anchor+1, newLine)
+				anchor++
+			}
+			continue
+		}
+
+		anchor = pos
+		changed := len(missing) > 0
+		lines[pos].Hosts = append(append([]string(nil), lines[pos].Hosts...), missing...)
+		if baseLine, ok := findEntryLine(base.Lines, line); ok && line.Comment != baseLine.Comment && lines[pos].Comment == baseLine.Comment {
+			lines[pos].Comment = line.Comment // only ours changed the trailing comment
+			changed = true
+		}
+		if changed {
+			lines[pos].RegenRaw()
+		}
+	}
+
+	result := newHostsFromLines(ours.Path, lines)
+	return result, conflicts
+}
+
+// mergeEntries works out which ip/host pairs end up in the merged result, hostname by hostname
+func mergeEntries(base, ours, theirs *Hosts) (map[entry]struct{}, []MergeConflict) {
+	b, o, t := hostIPs(base), hostIPs(ours), hostIPs(theirs)
+
+	all := make(map[string]struct{})
+	for _, m := range []map[string][]string{b, o, t} {
+		for host := range m {
+			all[host] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(all))
+	for host := range all {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	var conflicts []MergeConflict
+	merged := make(map[entry]struct{})
+	for _, host := range names {
+		var ips []string
+		switch {
+		case equalStrings(o[host], t[host]), equalStrings(b[host], t[host]):
+			ips = o[host]
+		case equalStrings(b[host], o[host]):
+			ips = t[host]
+		default:
+			ips = o[host]
+			conflicts = append(conflicts, MergeConflict{Host: host, Base: b[host], Ours: o[host], Theirs: t[host]})
+		}
+		for _, ip := range ips {
+			merged[entry{ip, host}] = struct{}{}
+		}
+	}
+
+	return merged, conflicts
+}
+
+// hostIPs maps every hostname to the sorted list of unique ips it's on
+func hostIPs(h *Hosts) map[string][]string {
+	m := make(map[string][]string)
+	for _, line := range h.Lines {
+		if !isEntryLine(line) {
+			continue
+		}
+		for _, host := range line.Hosts {
+			if !itemInSliceString(line.IP, m[host]) {
+				m[host] = append(m[host], line.IP)
+			}
+		}
+	}
+	for host := range m {
+		sort.Strings(m[host])
+	}
+	return m
+}
+
+// layoutChanges counts how many more times each comment/blank line appears in h than in base, negative when removed
+func layoutChanges(base, h *Hosts) map[string]int {
+	changes := make(map[string]int)
+	for _, line := range h.Lines {
+		if !isEntryLine(line) {
+			changes[line.Raw]++
+		}
+	}
+	for _, line := range base.Lines {
+		if !isEntryLine(line) {
+			changes[line.Raw]--
+		}
+	}
+	return changes
+}
+
+// removeLayoutLines drops the comment/blank lines ours removed, unless theirs already did
+func removeLayoutLines(lines []HostsLine, ours, theirs map[string]int) []HostsLine {
+	remove := make(map[string]int)
+	for raw, n := range ours {
+		if n >= 0 {
+			continue
+		}
+		count := -n
+		if t := theirs[raw]; t < 0 {
+			count += t // theirs already removed some of them
+		}
+		if count > 0 {
+			remove[raw] = count
+		}
+	}
+
+	out := lines[:0]
+	for _, line := range lines {
+		if !isEntryLine(line) && remove[line.Raw] > 0 {
+			remove[line.Raw]--
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// isEntryLine is true for lines with an ip and at least one hostname, everything else is merged by its raw contents
+func isEntryLine(line HostsLine) bool {
+	return !line.IsComment() && !line.IsMalformed() && line.IP != "" && len(line.Hosts) > 0
+}
+
+// sameEntryLine is true when both lines are for the same ip and share at least one hostname
+func sameEntryLine(a, b HostsLine) bool {
+	if !isEntryLine(a) || a.IP != b.IP {
+		return false
+	}
+	for _, host := range b.Hosts {
+		if itemInSliceString(host, a.Hosts) {
+			return true
+		}
+	}
+	return false
+}
+
+func findEntryLine(lines []HostsLine, line HostsLine) (HostsLine, bool) {
+	if pos := findLine(lines, 0, func(l HostsLine) bool { return sameEntryLine(l, line) }); pos >= 0 {
+		return lines[pos], true
+	}
+	return HostsLine{}, false
+}
+
+// findLine returns the position of the first line from start matching fn, falling back to searching the lines before
+// start, -1 if nothing matches
+func findLine(lines []HostsLine, start int, fn func(HostsLine) bool) int {
+	for pos := start; pos < len(lines); pos++ {
+		if fn(lines[pos]) {
+			return pos
+		}
+	}
+	for pos := 0; pos < start && pos < len(lines); pos++ {
+		if fn(lines[pos]) {
+			return pos
+		}
+	}
+	return -1
+}
+
+func insertLine(lines []HostsLine, pos int, line HostsLine) []HostsLine {
+	lines = append(lines, HostsLine{})
+	copy(lines[pos+1:], lines[pos:])
+	lines[pos] = line
+	return lines
+}
@@ -0,0 +1,103 @@
+package hostsfile
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadHosts(t *testing.T, lines ...string) *Hosts {
+	h := newHosts()
+	assert.Nil(t, h.loadString(strings.Join(lines, eol)))
+	return h
+}
+
+func TestMerge3(t *testing.T) {
+	base := loadHosts(t,
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1 app",
+		"10.0.0.2 db # primary",
+		"# footer",
+	)
+	ours := loadHosts(t,
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1 app api",
+		"# ours",
+		"10.0.0.5 cache",
+		"10.0.0.2 db # replica",
+		"# footer",
+	)
+	theirs := loadHosts(t,
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.2 db # primary",
+		"10.0.0.9 theirs",
+	)
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	assert.Len(t, conflicts, 0)
+	assert.Equal(t, strings.Join([]string{
+		"# header",
+		"127.0.0.1 localhost",
+		"10.0.0.1 api",
+		"# ours",
+		"10.0.0.5 cache",
+		"10.0.0.2 db # replica",
+		"10.0.0.9 theirs",
+		"",
+	}, eol), merged.String())
+	assert.True(t, merged.Has("10.0.0.5", "cache"))
+	assert.False(t, merged.HasHostname("app"))
+}
+
+func TestMerge3_Conflict(t *testing.T) {
+	base := loadHosts(t, "10.0.0.1 app", "10.0.0.2 db")
+	ours := loadHosts(t, "10.0.0.3 app", "10.0.0.2 db", "10.0.0.7 new")
+	theirs := loadHosts(t, "10.0.0.4 app", "10.0.0.2 db", "10.0.0.8 new")
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	assert.Equal(t, []MergeConflict{
+		{Host: "app", Base: []string{"10.0.0.1"}, Ours: []string{"10.0.0.3"}, Theirs: []string{"10.0.0.4"}},
+		{Host: "new", Ours: []string{"10.0.0.7"}, Theirs: []string{"10.0.0.8"}},
+	}, conflicts)
+
+	// ours wins
+	assert.True(t, merged.Has("10.0.0.3", "app"))
+	assert.False(t, merged.HasIP("10.0.0.4"))
+	assert.True(t, merged.Has("10.0.0.7", "new"))
+
+	// both sides making the same change is not a conflict
+	_, conflicts = Merge3(base, ours, ours)
+	assert.Len(t, conflicts, 0)
+}
+
+func TestHosts_FlushConflictMerge(t *testing.T) {
+	hosts := newTempHosts(t, "127.0.0.1 localhost\n10.0.0.1 app\n")
+	hosts.Conflict = ConflictMerge
+	assert.Nil(t, hosts.Add("10.0.0.2", "ours"))
+
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte("# theirs\n127.0.0.1 localhost\n10.0.0.1 app\n"), 0644))
+	assert.Nil(t, hosts.Flush())
+
+	data, err := os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "# theirs"+eol+"127.0.0.1 localhost"+eol+"10.0.0.1 app"+eol+"10.0.0.2 ours"+eol, string(data))
+
+	// conflicting change leaves the file alone
+	assert.Nil(t, hosts.Add("10.0.0.3", "app"))
+	assert.Nil(t, os.WriteFile(hosts.Path, []byte("10.0.0.4 app\n"), 0644))
+	err = hosts.Flush()
+	var mergeErr *MergeError
+	assert.True(t, errors.As(err, &mergeErr))
+	assert.ErrorIs(t, err, ErrConcurrentModification)
+	assert.Len(t, mergeErr.Conflicts, 1)
+
+	data, err = os.ReadFile(hosts.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.4 app\n", string(data))
+}
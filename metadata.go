@@ -0,0 +1,180 @@
+package hostsfile
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metadataRegex matches a "@key=value" annotation in a trailing comment, annotations are whitespace separated so any
+// other text in the comment is left alone e.g. "# dev box @owner=ci @tags=web,api"
+var metadataRegex = regexp.MustCompile(`(^|\s)@([A-Za-z0-9_.-]+)=(\S+)`)
+
+// parseMetadata returns the annotations in comment, nil when there are none
+func parseMetadata(comment string) map[string]string {
+	var metadata map[string]string
+	for _, match := range metadataRegex.FindAllStringSubmatch(comment, -1) {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		if _, ok := metadata[match[2]]; !ok { // first one wins
+			metadata[match[2]] = match[3]
+		}
+	}
+	return metadata
+}
+
+// metadataComment returns the trailing comment of the entry with the annotations updated to match Metadata, a nil
+// Metadata leaves it as it is
+func (l *HostsLine) metadataComment() string {
+	entryComment := l.entryComment()
+	if l.Metadata == nil || equalMetadata(parseMetadata(entryComment), l.Metadata) {
+		return entryComment
+	}
+
+	// update or drop the existing annotations in place
+	seen := make(map[string]bool)
+	var b strings.Builder
+	last := 0
+	for _, idx := range metadataRegex.FindAllStringSubmatchIndex(entryComment, -1) {
+		key := entryComment[idx[4]:idx[5]]
+		value, ok := l.Metadata[key]
+		b.WriteString(entryComment[last:idx[0]])
+		if ok && !seen[key] {
+			b.WriteString(entryComment[idx[0]:idx[4]] + key + "=" + value)
+		}
+		seen[key] = true
+		last = idx[1]
+	}
+	b.WriteString(entryComment[last:])
+	comment := b.String()
+
+	// and append the new ones
+	var keys []string
+	for key := range l.Metadata {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.TrimSpace(comment) == "" {
+			comment = " "
+		} else {
+			comment += " "
+		}
+		comment += "@" + key + "=" + l.Metadata[key]
+	}
+	if strings.TrimSpace(comment) == "" {
+		return ""
+	}
+	return comment
+}
+
+// SetMetadata sets the annotation key to value in the trailing comment
+func (l *HostsLine) SetMetadata(key, value string) error {
+	if err := validateMetadata(key, value); err != nil {
+		return err
+	}
+	l.ensureMetadata()
+	l.Metadata[key] = value
+	l.RegenRaw()
+	return nil
+}
+
+// DeleteMetadata removes the annotation key from the trailing comment
+func (l *HostsLine) DeleteMetadata(key string) {
+	l.ensureMetadata()
+	delete(l.Metadata, key)
+	l.RegenRaw()
+}
+
+// HasMetadata returns true if the line has the annotation key, when value isn't empty it also has to match the value or
+// one of the comma separated values e.g. "@tags=web,api" matches "web"
+func (l *HostsLine) HasMetadata(key, value string) bool {
+	current, ok := l.Metadata[key]
+	if !ok {
+		return false
+	}
+	return value == "" || current == value || itemInSliceString(value, strings.Split(current, ","))
+}
+
+// ensureMetadata makes Metadata a copy of the current annotations, lines copied by splitting or formatting share the
+// same map so it's never changed in place
+func (l *HostsLine) ensureMetadata() {
+	current := l.Metadata
+	if current == nil {
+		current = parseMetadata(l.entryComment())
+	}
+	l.Metadata = make(map[string]string, len(current))
+	for key, value := range current {
+		l.Metadata[key] = value
+	}
+}
+
+func validateMetadata(key, value string) error {
+	if !metadataRegex.MatchString("@"+key+"="+value) || strings.ContainsAny(key+value, " \t") {
+		return fmt.Errorf("invalid metadata %q=%q", key, value)
+	}
+	return nil
+}
+
+func equalMetadata(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if current, ok := b[key]; !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMetadata sets the annotation key to value on every entry line for ip
+func (h *Hosts) SetMetadata(ip, key, value string) error {
+	return h.apply(edit{op: opSetMetadata, ip: ip, key: key, value: value})
+}
+
+// DeleteMetadata removes the annotation key from every entry line for ip
+func (h *Hosts) DeleteMetadata(ip, key string) error {
+	return h.apply(edit{op: opDeleteMetadata, ip: ip, key: key})
+}
+
+// FindByMetadata returns the entry lines with the annotation key, matching value the same as HostsLine.HasMetadata
+func (h *Hosts) FindByMetadata(key, value string) []HostsLine {
+	defer h.lockRead()()
+	var lines []HostsLine
+	for _, line := range h.Lines {
+		if (line.Disabled || !line.IsComment()) && line.HasMetadata(key, value) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (h *Hosts) setMetadata(ip, key, value string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	if err := validateMetadata(key, value); err != nil {
+		return err
+	}
+	for _, pos := range h.ips.get(ip) {
+		if err := h.Lines[pos].SetMetadata(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hosts) deleteMetadata(ip, key string) error {
+	if err := h.validateIP(ip); err != nil {
+		return err
+	}
+	for _, pos := range h.ips.get(ip) {
+		h.Lines[pos].DeleteMetadata(key)
+	}
+	return nil
+}
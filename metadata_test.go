@@ -0,0 +1,81 @@
+package hostsfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHostsLine_Metadata(t *testing.T) {
+	hl := NewHostsLine("10.0.0.1 app # dev box @owner=ci @tags=web,api email me@example.com")
+	assert.Equal(t, map[string]string{"owner": "ci", "tags": "web,api"}, hl.Metadata)
+	assert.True(t, hl.HasMetadata("owner", "ci"))
+	assert.True(t, hl.HasMetadata("tags", "api"))
+	assert.True(t, hl.HasMetadata("tags", ""))
+	assert.False(t, hl.HasMetadata("owner", "bob"))
+
+	assert.Nil(t, NewHostsLine("10.0.0.1 app # a=b and @ not=this").Metadata)
+	assert.Equal(t, map[string]string{"owner": "ci"}, NewHostsLine("# 10.0.0.1 app # @owner=ci").Metadata)
+}
+
+func TestHostsLine_SetMetadata(t *testing.T) {
+	hl := NewHostsLine("10.0.0.1 app # dev box @owner=ci free text")
+
+	assert.Nil(t, hl.SetMetadata("owner", "bob"))
+	assert.Equal(t, "10.0.0.1 app # dev box @owner=bob free text", hl.Raw)
+
+	assert.Nil(t, hl.SetMetadata("ticket", "OPS-1"))
+	assert.Equal(t, "10.0.0.1 app # dev box @owner=bob free text @ticket=OPS-1", hl.Raw)
+	assert.Equal(t, " dev box @owner=bob free text @ticket=OPS-1", hl.Comment)
+
+	hl.DeleteMetadata("owner")
+	assert.Equal(t, "10.0.0.1 app # dev box free text @ticket=OPS-1", hl.Raw)
+
+	hl.Metadata["ticket"] = "OPS-2"
+	assert.Equal(t, "10.0.0.1 app # dev box free text @ticket=OPS-2", hl.ToRaw())
+
+	assert.NotNil(t, hl.SetMetadata("owner", "has space"))
+	assert.NotNil(t, hl.SetMetadata("", "value"))
+	assert.NotNil(t, hl.SetMetadata("owner", ""))
+
+	hl = NewHostsLine("10.0.0.1 app")
+	assert.Nil(t, hl.SetMetadata("owner", "ci"))
+	assert.Equal(t, "10.0.0.1 app # @owner=ci", hl.Raw)
+	hl.DeleteMetadata("owner")
+	assert.Equal(t, "10.0.0.1 app", hl.Raw)
+}
+
+func TestHosts_Metadata(t *testing.T) {
+	hosts := loadHosts(t,
+		"# owner=ci isn't an annotation",
+		"10.0.0.1 app # @owner=ci",
+		"10.0.0.2 db",
+		"# 10.0.0.3 old # @owner=ci",
+	)
+
+	assert.Nil(t, hosts.SetMetadata("10.0.0.2", "owner", "ci"))
+	assert.Len(t, hosts.FindByMetadata("owner", "ci"), 3)
+
+	assert.Nil(t, hosts.DeleteMetadata("10.0.0.1", "owner"))
+	assert.Equal(t, "10.0.0.1 app", hosts.Lines[1].Raw)
+	assert.Len(t, hosts.FindByMetadata("owner", ""), 2)
+
+	assert.NotNil(t, hosts.SetMetadata("10.0.0.2", "owner", "two words"))
+}
+
+func TestHosts_MetadataRoundTrip(t *testing.T) {
+	hosts := loadHosts(t,
+		"10.0.0.1 b # first @owner=ci @tags=web",
+		"10.0.0.1 a # second @owner=bob @ticket=OPS-1",
+	)
+
+	hosts.Clean()
+	assert.Len(t, hosts.Lines, 1)
+	assert.Equal(t, map[string]string{"owner": "ci", "tags": "web", "ticket": "OPS-1"}, hosts.Lines[0].Metadata)
+	assert.Equal(t, " first @owner=ci @tags=web  second @ticket=OPS-1", hosts.Lines[0].Comment)
+
+	reloaded := loadHosts(t, strings.TrimSuffix(hosts.String(), eol))
+	assert.Equal(t, hosts.Lines[0].Metadata, reloaded.Lines[0].Metadata)
+	assert.Equal(t, hosts.Lines[0].Comment, reloaded.Lines[0].Comment)
+}
@@ -0,0 +1,165 @@
+package hostsfile
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// Validation sets how strictly ips and hostnames passed to Add, AddRaw and Remove are checked
+type Validation int
+
+const (
+	// ValidateStrict requires a valid ip and hostnames that are valid dns names, this is the default
+	ValidateStrict Validation = iota
+	// ValidateIP only requires a valid ip, hostnames just can't contain whitespace or the comment char
+	ValidateIP
+	// ValidateNone accepts anything that won't break the line format
+	ValidateNone
+)
+
+// config holds the per instance settings made with Options, zero values fall back to the package level defaults
+type config struct {
+	hostsPerLine    int    // max hosts per line used by Clean and Flush, 0 uses HostsPerLine and negative is unlimited
+	eol             string // line ending to write with instead of the one detected on Load
+	bom             *bool  // write a BOM or not instead of detecting it on Load
+	validation      Validation
+	profile         *Profile         // set with WithProfile, nil uses the defaults of the platform
+	format          FormatStyle      // applied on every Flush
+	includeDisabled bool             // Has, HasIP and HasHostname also match disabled entries
+	now             func() time.Time // clock used for expiring entries, nil uses time.Now
+	historyDepth    int              // number of edits that can be undone, 0 disables the history
+	keepHistory     bool             // keep the history after Flush instead of resetting it
+	watchInterval   time.Duration    // how often Watch polls, 0 uses defaultWatchInterval
+	lintRules       lintSeverities   // severities set with WithLintRule, unset rules use defaultSeverities
+	snapshots       bool             // publish a Snapshot after every change, see WithSnapshots
+}
+
+// Option configures a Hosts created with New
+type Option func(*Hosts)
+
+// New returns a new instance of Hosts configured by opts and loads the hosts file. Without WithPath the default hosts
+// file path is used, the same as NewHosts.
+func New(opts ...Option) (*Hosts, error) {
+	hosts := &Hosts{
+		ips:   newLookup(),
+		hosts: newLookup(),
+	}
+	for _, opt := range opts {
+		opt(hosts)
+	}
+	if hosts.Path == "" && hosts.config.profile != nil {
+		hosts.Path = os.ExpandEnv(filepath.FromSlash(hosts.config.profile.Path))
+	}
+	if hosts.Path == "" {
+		hosts.Path = defaultHostsFilePath()
+	}
+
+	if err := hosts.Load(); err != nil {
+		return hosts, err
+	}
+
+	return hosts, nil
+}
+
+// WithPath sets the path of the hosts file
+func WithPath(path string) Option {
+	return func(h *Hosts) {
+		h.Path = path
+	}
+}
+
+// WithHostsPerLine sets the max number of hosts per line used by Clean and Flush, negative is unlimited
+func WithHostsPerLine(count int) Option {
+	return func(h *Hosts) {
+		h.config.hostsPerLine = count
+		if count == 0 {
+			h.config.hostsPerLine = -1
+		}
+	}
+}
+
+// WithLineEnding sets the line ending used when writing, it's kept instead of the one detected on Load
+func WithLineEnding(eol string) Option {
+	return func(h *Hosts) {
+		h.config.eol = eol
+		h.EOL = eol
+	}
+}
+
+// WithBOM sets if a UTF-8 byte order mark is written at the start of the file, it's kept instead of detecting it on Load
+func WithBOM(bom bool) Option {
+	return func(h *Hosts) {
+		h.config.bom = &bom
+		h.BOM = bom
+	}
+}
+
+// WithValidation sets how strictly ips and hostnames are validated
+func WithValidation(validation Validation) Option {
+	return func(h *Hosts) {
+		h.config.validation = validation
+	}
+}
+
+// WithBackup takes a backup with manager before every Flush, see AutoBackup
+func WithBackup(manager *BackupManager) Option {
+	return func(h *Hosts) {
+		h.AutoBackup = manager
+	}
+}
+
+// WithStorage sets where the hosts file is read from and written to
+func WithStorage(storage Storage) Option {
+	return func(h *Hosts) {
+		h.storage = storage
+	}
+}
+
+// WithConflictMode sets what Flush does when the file was changed on disk since Load
+func WithConflictMode(mode ConflictMode) Option {
+	return func(h *Hosts) {
+		h.Conflict = mode
+	}
+}
+
+// defaultHostsFilePath returns the HOSTS_PATH env var if set, otherwise HostsFilePath
+func defaultHostsFilePath() string {
+	if env, isset := os.LookupEnv("HOSTS_PATH"); isset && len(env) > 0 {
+		return os.ExpandEnv(filepath.FromSlash(env))
+	}
+	return os.ExpandEnv(filepath.FromSlash(HostsFilePath))
+}
+
+// maxHostsPerLine returns the hosts per line limit for this instance
+func (h *Hosts) maxHostsPerLine() int {
+	if h.config.hostsPerLine == 0 {
+		return h.profile().HostsPerLine
+	}
+	return h.config.hostsPerLine
+}
+
+func (h *Hosts) validateIP(ip string) error {
+	if h.config.validation < ValidateNone && net.ParseIP(ip) == nil {
+		return fmt.Errorf("%q is an invalid IP address", ip)
+	}
+	if ip == "" || strings.ContainsAny(ip, " \t"+commentChar) {
+		return fmt.Errorf("%q is an invalid IP address", ip)
+	}
+	return nil
+}
+
+func (h *Hosts) validateHost(host string) error {
+	if h.config.validation < ValidateIP && !govalidator.IsDNSName(host) {
+		return fmt.Errorf("hostname is not a valid dns name: %s", host)
+	}
+	if host == "" || strings.ContainsAny(host, " \t"+commentChar) {
+		return fmt.Errorf("hostname is not valid: %q", host)
+	}
+	return nil
+}
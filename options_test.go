@@ -0,0 +1,70 @@
+package hostsfile
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{
+		"hosts": []byte("127.0.0.1 localhost\n"),
+	})
+	backups := NewBackupManager("hosts", "")
+
+	hosts, err := New(
+		WithPath("hosts"),
+		WithStorage(storage),
+		WithHostsPerLine(2),
+		WithLineEnding("\r\n"),
+		WithBOM(true),
+		WithBackup(backups),
+		WithConflictMode(ConflictMerge),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "hosts", hosts.Path)
+	assert.Equal(t, backups, hosts.AutoBackup)
+	assert.Equal(t, ConflictMerge, hosts.Conflict)
+
+	// the line ending option wins over the one detected on load
+	assert.Equal(t, "\r\n", hosts.EOL)
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "c", "b", "a"))
+	hosts.Clean()
+	assert.Equal(t, "10.0.0.1 a b\r\n10.0.0.1 c\r\n127.0.0.1 localhost\r\n", hosts.String())
+
+	// backups are only taken of hosts files on disk
+	assert.ErrorIs(t, hosts.Flush(), ErrBackupUnsupported)
+	hosts.AutoBackup = nil
+	assert.Nil(t, hosts.Flush())
+	assert.Equal(t, "\xef\xbb\xbf10.0.0.1 a b\r\n10.0.0.1 c\r\n127.0.0.1 localhost\r\n", readStorage(t, storage, "hosts"))
+
+	// defaults to the hosts file path
+	t.Setenv("HOSTS_PATH", newTempHosts(t, "").Path)
+	hosts, err = New()
+	assert.Nil(t, err)
+	assert.Equal(t, os.Getenv("HOSTS_PATH"), hosts.Path)
+}
+
+func TestNew_Validation(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": nil})
+
+	hosts, err := New(WithPath("hosts"), WithStorage(storage))
+	assert.Nil(t, err)
+	assert.Error(t, hosts.Add("127.0.0.1", "host%"))
+	assert.Error(t, hosts.Add("not-an-ip", "host"))
+
+	hosts, err = New(WithPath("hosts"), WithStorage(storage), WithValidation(ValidateIP))
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("127.0.0.1", "host%"))
+	assert.Nil(t, hosts.AddRaw("127.0.0.2 host_%"))
+	assert.Error(t, hosts.Add("not-an-ip", "host"))
+	assert.Error(t, hosts.Add("127.0.0.1", "two hosts"))
+
+	hosts, err = New(WithPath("hosts"), WithStorage(storage), WithValidation(ValidateNone))
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("not-an-ip", "host%"))
+	assert.Error(t, hosts.Add("not an ip", "host"))
+	assert.Error(t, hosts.Add("127.0.0.1", "host#comment"))
+}
@@ -9,5 +9,4 @@ var (
 	eol           = "\n"
 )
 
-func (h *Hosts) preFlush() error  { return nil }
-func (h *Hosts) postFlush() error { return nil }
+const splitHostsOnFlush = false
@@ -0,0 +1,134 @@
+package hostsfile
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPlanDrift is returned by Apply when the hosts file changed since the plan was made
+var ErrPlanDrift = errors.New("hosts file changed since the plan was made")
+
+// Operation is a recorded edit in a Plan, the fields used depend on Op
+type Operation struct {
+	Op      string      `json:"op"`
+	Section string      `json:"section,omitempty"`
+	IP      string      `json:"ip,omitempty"`
+	Hosts   []string    `json:"hosts,omitempty"` // hostnames, or raw lines for add_raw, replace_line and set_lines
+	Count   int         `json:"count,omitempty"`
+	Line    int         `json:"line,omitempty"`
+	Style   FormatStyle `json:"style,omitempty"`
+	Key     string      `json:"key,omitempty"`
+	Value   string      `json:"value,omitempty"`
+	Time    *time.Time  `json:"time,omitempty"`
+	Old     *string     `json:"old,omitempty"` // the line replace_line replaced, Apply fails when it's changed
+}
+
+// Plan is the edits made to a Hosts since it was loaded and the checksum of the file they were made against. It can be
+// serialized to JSON, reviewed and later applied with Hosts.Apply.
+type Plan struct {
+	Path       string      `json:"path"`
+	Checksum   string      `json:"checksum"` // hex sha256 of the hosts file the plan was made from
+	Operations []Operation `json:"operations"`
+	Changes    []Change    `json:"changes"` // what the operations change, see Diff
+	Diff       string      `json:"diff"`    // unified diff of the hosts file before and after the operations
+}
+
+// Plan returns the edits made since Load without writing anything. Only edits made through the Hosts methods are
+// recorded, changes made to Lines directly aren't part of the plan.
+func (h *Hosts) Plan() (*Plan, error) {
+	defer h.lockRead()()
+	if h.checksum == nil {
+		return nil, fmt.Errorf("hosts file %q wasn't loaded, nothing to plan against", h.Path)
+	}
+
+	d := diff(h.Path, h.Path, h.base, h.Lines)
+	plan := &Plan{
+		Path:       h.Path,
+		Checksum:   hex.EncodeToString(h.checksum),
+		Operations: make([]Operation, 0, len(h.pending)),
+		Changes:    d.Changes,
+		Diff:       d.String(),
+	}
+	for _, e := range h.pending {
+		plan.Operations = append(plan.Operations, e.operation())
+	}
+	return plan, nil
+}
+
+// Apply reloads the hosts file, checks it still matches the file the plan was made from and replays the plan's
+// operations before flushing. When the file changed ErrPlanDrift is returned without writing anything.
+func (h *Hosts) Apply(plan *Plan) error {
+	defer h.lockWrite()()
+	if err := h.reload(); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.checksum) != plan.Checksum {
+		return ErrPlanDrift
+	}
+
+	for i, op := range plan.Operations {
+		e, err := op.edit()
+		if err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+		if err := h.applyEdit(e); err != nil {
+			return fmt.Errorf("operation %d %s: %w", i, op.Op, err)
+		}
+	}
+
+	// a plan is only valid against what it was made from so never let Flush reapply or merge it
+	conflict := h.Conflict
+	h.Conflict = ConflictFail
+	defer func() { h.Conflict = conflict }()
+	if err := h.flush(); err != nil {
+		if errors.Is(err, ErrConcurrentModification) {
+			return ErrPlanDrift
+		}
+		return err
+	}
+	return nil
+}
+
+func (e edit) operation() Operation {
+	op := Operation{
+		Op:      string(e.op),
+		Section: e.section,
+		IP:      e.ip,
+		Hosts:   e.hosts,
+		Count:   e.count,
+		Line:    e.line,
+		Style:   e.style,
+		Key:     e.key,
+		Value:   e.value,
+		Old:     e.old,
+	}
+	if !e.time.IsZero() {
+		t := e.time
+		op.Time = &t
+	}
+	return op
+}
+
+func (op Operation) edit() (edit, error) {
+	e := edit{
+		op:      editOp(op.Op),
+		section: op.Section,
+		ip:      op.IP,
+		hosts:   op.Hosts,
+		count:   op.Count,
+		line:    op.Line,
+		style:   op.Style,
+		key:     op.Key,
+		value:   op.Value,
+		old:     op.Old,
+	}
+	if op.Time != nil {
+		e.time = *op.Time
+	}
+	if (e.op == opRemoveByHostname || e.op == opReplaceLine) && len(e.hosts) != 1 {
+		return e, fmt.Errorf("%s needs exactly one host", e.op)
+	}
+	return e, nil
+}
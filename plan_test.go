@@ -0,0 +1,78 @@
+package hostsfile
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_PlanApply(t *testing.T) {
+	original := "127.0.0.1 localhost\n10.0.0.1 app\n10.0.0.2 db old\n"
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte(original)})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "api"))
+	assert.Nil(t, hosts.Remove("10.0.0.2", "db"))
+	assert.Nil(t, hosts.RemoveByHostname("old"))
+	assert.Nil(t, hosts.AddUntil("10.0.0.3", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), "tmp"))
+	hosts.Clean()
+
+	plan, err := hosts.Plan()
+	assert.Nil(t, err)
+	assert.Equal(t, original, readStorage(t, storage, "hosts"), "planning doesn't write")
+	assert.Len(t, plan.Operations, 5)
+	assert.Equal(t, Operation{Op: "add", IP: "10.0.0.1", Hosts: []string{"api"}}, plan.Operations[0])
+	assert.Equal(t, "clean", plan.Operations[4].Op)
+	assert.Contains(t, plan.Diff, "+10.0.0.1 api app")
+	assert.Contains(t, plan.Changes, Change{Kind: ChangeRemoved, IP: "10.0.0.2", Hosts: []string{"db"}, OldLine: 3})
+
+	// round trip through json as a reviewer would see it
+	data, err := json.Marshal(plan)
+	assert.Nil(t, err)
+	var reviewed Plan
+	assert.Nil(t, json.Unmarshal(data, &reviewed))
+
+	other, err := New(WithStorage(storage), WithPath("hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, other.Apply(&reviewed))
+	assert.Equal(t, hosts.String(), readStorage(t, storage, "hosts"))
+	assert.True(t, other.Has("10.0.0.3", "tmp"))
+}
+
+func TestHosts_ApplyDrift(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("10.0.0.1", "app"))
+	plan, err := hosts.Plan()
+	assert.Nil(t, err)
+
+	changed := "127.0.0.1 localhost\n10.0.0.9 someone-else\n"
+	assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+		_, err := io.WriteString(w, changed)
+		return err
+	}))
+
+	assert.ErrorIs(t, hosts.Apply(plan), ErrPlanDrift)
+	assert.Equal(t, changed, readStorage(t, storage, "hosts"))
+}
+
+func TestHosts_PlanNotLoaded(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost")
+	_, err := hosts.Plan()
+	assert.NotNil(t, err)
+
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	loaded, err := New(WithStorage(storage), WithPath("hosts"))
+	assert.Nil(t, err)
+	plan, err := loaded.Plan()
+	assert.Nil(t, err)
+	plan.Operations = append(plan.Operations, Operation{Op: "remove_by_hostname"}, Operation{Op: "bogus"})
+	assert.NotNil(t, loaded.Apply(plan))
+	plan.Operations = []Operation{{Op: "bogus"}}
+	assert.NotNil(t, loaded.Apply(plan))
+}
@@ -0,0 +1,172 @@
+package hostsfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Profile bundles everything that differs between platforms' hosts files so a hosts file for any platform can be
+// edited from any other, e.g. a Windows hosts file on a mounted disk from Linux
+type Profile struct {
+	Name           string // Name used with ProfileByName
+	Path           string // Default path of the hosts file, environment variables are expanded
+	EOL            string // Line ending for files that don't have one yet
+	HostsPerLine   int    // Max hosts per line used by Clean, -1 is unlimited
+	SplitOnFlush   bool   // Always split lines to HostsPerLine on Flush
+	DefaultContent string // Contents of a freshly installed hosts file, lines separated by \n
+}
+
+var (
+	ProfileLinux = Profile{
+		Name:         "linux",
+		Path:         "/etc/hosts",
+		EOL:          "\n",
+		HostsPerLine: -1,
+		DefaultContent: strings.Join([]string{
+			"127.0.0.1\tlocalhost",
+			"::1\tlocalhost ip6-localhost ip6-loopback",
+			"ff02::1\tip6-allnodes",
+			"ff02::2\tip6-allrouters",
+		}, "\n"),
+	}
+
+	ProfileMacOS = Profile{
+		Name:         "macos",
+		Path:         "/etc/hosts",
+		EOL:          "\n",
+		HostsPerLine: -1,
+		DefaultContent: strings.Join([]string{
+			"##",
+			"# Host Database",
+			"#",
+			"# localhost is used to configure the loopback interface",
+			"# when the system is booting.  Do not change this entry.",
+			"##",
+			"127.0.0.1\tlocalhost",
+			"255.255.255.255\tbroadcasthost",
+			"::1             localhost",
+		}, "\n"),
+	}
+
+	ProfileWindows = Profile{
+		Name:         "windows",
+		Path:         "${SystemRoot}/System32/drivers/etc/hosts",
+		EOL:          "\r\n",
+		HostsPerLine: 9,
+		SplitOnFlush: true,
+		DefaultContent: strings.Join([]string{
+			"# Copyright (c) 1993-2009 Microsoft Corp.",
+			"#",
+			"# This is a sample HOSTS file used by Microsoft TCP/IP for Windows.",
+			"#",
+			"# This file contains the mappings of IP addresses to host names. Each",
+			"# entry should be kept on an individual line. The IP address should",
+			"# be placed in the first column followed by the corresponding host name.",
+			"# The IP address and the host name should be separated by at least one",
+			"# space.",
+			"#",
+			"# Additionally, comments (such as these) may be inserted on individual",
+			"# lines or following the machine name denoted by a '#' symbol.",
+			"#",
+			"# For example:",
+			"#",
+			"#      102.54.94.97     rhino.acme.com          # source server",
+			"#       38.25.63.10     x.acme.com              # x client host",
+			"",
+			"# localhost name resolution is handled within DNS itself.",
+			"#\t127.0.0.1       localhost",
+			"#\t::1             localhost",
+		}, "\n"),
+	}
+
+	ProfileWSL = Profile{
+		Name:         "wsl",
+		Path:         "/etc/hosts",
+		EOL:          "\n",
+		HostsPerLine: -1,
+		DefaultContent: strings.Join([]string{
+			"# This file was automatically generated by WSL. To stop automatic generation of this file, add the following entry to /etc/wsl.conf:",
+			"# [network]",
+			"# generateHosts = false",
+			"127.0.0.1\tlocalhost",
+			"127.0.1.1\tlocalhost.localdomain\tlocalhost",
+			"",
+			"# The following lines are desirable for IPv6 capable hosts",
+			"::1     ip6-localhost ip6-loopback",
+			"fe00::0 ip6-localnet",
+			"ff00::0 ip6-mcastprefix",
+			"ff02::1 ip6-allnodes",
+			"ff02::2 ip6-allrouters",
+		}, "\n"),
+	}
+
+	ProfileAndroid = Profile{
+		Name:         "android",
+		Path:         "/system/etc/hosts",
+		EOL:          "\n",
+		HostsPerLine: -1,
+		DefaultContent: strings.Join([]string{
+			"127.0.0.1       localhost",
+			"::1             ip6-localhost",
+		}, "\n"),
+	}
+)
+
+// Profiles returns all the built in profiles
+func Profiles() []Profile {
+	return []Profile{ProfileLinux, ProfileMacOS, ProfileWindows, ProfileWSL, ProfileAndroid}
+}
+
+// ProfileByName returns the built in profile with the name e.g. "windows"
+func ProfileByName(name string) (Profile, error) {
+	for _, p := range Profiles() {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown hosts file profile: %q", name)
+}
+
+// Default returns a Hosts with the default contents of the profile, nothing is read from disk
+func (p Profile) Default() (*Hosts, error) {
+	hosts := newHostsFromLines("", nil)
+	hosts.config.profile = &p
+	if _, err := hosts.ReadFrom(strings.NewReader(strings.ReplaceAll(p.DefaultContent, "\n", p.EOL) + p.EOL)); err != nil {
+		return hosts, err
+	}
+	hosts.Path = os.ExpandEnv(filepath.FromSlash(p.Path))
+	return hosts, nil
+}
+
+// WithProfile uses the profile's path (unless WithPath is used), line ending and hosts per line instead of the
+// defaults of the platform the program was built for
+func WithProfile(p Profile) Option {
+	return func(h *Hosts) {
+		h.config.profile = &p
+	}
+}
+
+// profile returns the profile set with WithProfile, or one made from the package level defaults
+func (h *Hosts) profile() Profile {
+	if h.config.profile != nil {
+		return *h.config.profile
+	}
+	return Profile{
+		Path:         HostsFilePath,
+		EOL:          eol,
+		HostsPerLine: HostsPerLine,
+		SplitOnFlush: splitHostsOnFlush,
+	}
+}
+
+func (h *Hosts) preFlush() error {
+	if h.profile().SplitOnFlush {
+		h.hostsPerLine(h.maxHostsPerLine())
+	}
+	h.format(h.config.format)
+	return nil
+}
+
+func (h *Hosts) postFlush() error { return nil }
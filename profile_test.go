@@ -0,0 +1,68 @@
+package hostsfile
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileByName(t *testing.T) {
+	for _, name := range []string{"linux", "macos", "windows", "wsl", "android", "Windows"} {
+		p, err := ProfileByName(name)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, p.Path)
+		assert.NotEmpty(t, p.DefaultContent)
+	}
+
+	_, err := ProfileByName("plan9")
+	assert.Error(t, err)
+}
+
+func TestProfile_Default(t *testing.T) {
+	hosts, err := ProfileMacOS.Default()
+	assert.Nil(t, err)
+	assert.Len(t, hosts.Lines, 9)
+	assert.Equal(t, "/etc/hosts", hosts.Path)
+	assert.True(t, hosts.Has("::1", "localhost"))
+
+	hosts, err = ProfileWindows.Default()
+	assert.Nil(t, err)
+	assert.Len(t, hosts.Lines, 21)
+	assert.Equal(t, "\r\n", hosts.EOL)
+	assert.False(t, hosts.HasIP("127.0.0.1"))
+}
+
+func TestWithProfile_Windows(t *testing.T) {
+	fp := newTempHosts(t, "").Path
+	hosts, err := New(WithProfile(ProfileWindows), WithPath(fp))
+	assert.Nil(t, err)
+	assert.Equal(t, fp, hosts.Path)
+
+	assert.Nil(t, hosts.Add("127.0.0.1", "h1", "h2", "h3", "h4", "h5", "h6", "h7", "h8", "h9", "h10"))
+	assert.Nil(t, hosts.Flush())
+
+	data, err := os.ReadFile(fp)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 h1 h2 h3 h4 h5 h6 h7 h8 h9\r\n127.0.0.1 h10\r\n", string(data))
+
+	// the per instance option still wins over the profile
+	hosts, err = New(WithProfile(ProfileWindows), WithPath(fp), WithHostsPerLine(5))
+	assert.Nil(t, err)
+	hosts.Clean()
+	assert.Len(t, hosts.Lines, 2)
+	assert.Len(t, hosts.Lines[0].Hosts, 5)
+}
+
+func TestWithProfile_Linux(t *testing.T) {
+	fp := newTempHosts(t, "").Path
+	hosts, err := New(WithProfile(ProfileLinux), WithPath(fp))
+	assert.Nil(t, err)
+
+	assert.Nil(t, hosts.Add("127.0.0.1", "h1", "h2", "h3", "h4", "h5", "h6", "h7", "h8", "h9", "h10"))
+	assert.Nil(t, hosts.Flush())
+
+	data, err := os.ReadFile(fp)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1 h1 h2 h3 h4 h5 h6 h7 h8 h9 h10\n", string(data))
+}
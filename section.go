@@ -0,0 +1,299 @@
+package hostsfile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSectionName is returned when editing a section whose name can't be written to a marker and found again
+var ErrInvalidSectionName = errors.New("section name has to be a single line without # or surrounding spaces")
+
+const (
+	sectionBegin = "BEGIN"
+	sectionEnd   = "END"
+)
+
+// Section is a named block of the hosts file between "# BEGIN name" and "# END name" marker lines, letting a program
+// manage its own entries without touching anything outside the block. Sections are found by their markers so any
+// section written to the file is picked up again on Load.
+type Section struct {
+	h    *Hosts
+	name string
+}
+
+// Section returns a handle for the named section, the markers are only added to the file once something is added.
+// Editing a section with an invalid name returns ErrInvalidSectionName.
+func (h *Hosts) Section(name string) *Section {
+	return &Section{h: h, name: name}
+}
+
+// Sections returns the names of all sections in the order they appear
+func (h *Hosts) Sections() []string {
+	defer h.lockRead()()
+	var names []string
+	for _, line := range h.Lines {
+		if kind, name := sectionMarker(line); kind == sectionBegin && !itemInSliceString(name, names) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Name of the section as used in the markers
+func (s *Section) Name() string {
+	return s.name
+}
+
+// Lines returns a copy of the lines between the markers
+func (s *Section) Lines() []HostsLine {
+	defer s.h.lockRead()()
+	begin, end, ok := s.bounds()
+	if !ok {
+		return nil
+	}
+	lines := make([]HostsLine, end-begin-1)
+	copy(lines, s.h.Lines[begin+1:end])
+	return lines
+}
+
+// Add an entry to the section, creating the section at the end of the file if it doesn't exist yet. Hosts already in the
+// section for a different ip are moved, entries outside the section are left alone.
+func (s *Section) Add(ip string, hosts ...string) error {
+	return s.h.apply(edit{op: opSectionAdd, section: s.name, ip: ip, hosts: hosts})
+}
+
+// Remove hosts from an ip in the section, when no hosts are passed every line for the ip is removed from the section
+func (s *Section) Remove(ip string, hosts ...string) error {
+	return s.h.apply(edit{op: opSectionRemove, section: s.name, ip: ip, hosts: hosts})
+}
+
+// Clear removes everything in the section but keeps the markers, Err returns why when nothing was cleared
+func (s *Section) Clear() {
+	s.h.applyQuiet(edit{op: opSectionClear, section: s.name})
+}
+
+func (s *Section) add(ip string, hosts ...string) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if err := s.h.validateIP(ip); err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if err := s.h.validateHost(host); err != nil {
+			return err
+		}
+	}
+
+	begin, end := s.ensure()
+
+	// move hosts pointing to other ips within the section
+	lines := make([]HostsLine, 0, end-begin-1)
+	for _, line := range s.h.Lines[begin+1 : end] {
+		if line.IsComment() || line.IP == ip {
+			lines = append(lines, line)
+			continue
+		}
+		newHosts := line.Hosts
+		for _, host := range hosts {
+			newHosts = removeFromSliceString(host, append([]string(nil), newHosts...))
+		}
+		if len(newHosts) == 0 && len(line.Hosts) > 0 {
+			continue
+		}
+		if len(newHosts) != len(line.Hosts) {
+			line.Hosts = newHosts
+			line.RegenRaw()
+		}
+		lines = append(lines, line)
+	}
+
+	// add to the last line for the ip or start a new one before the end marker
+	loc := -1
+	for pos, line := range lines {
+		if line.IP == ip && !line.IsComment() {
+			loc = pos
+		}
+	}
+	if loc < 0 {
+		lines = append(lines, HostsLine{
+			Raw:   fmt.Sprintf("%s %s", ip, strings.Join(hosts, " ")),
+			IP:    ip,
+			Hosts: append([]string(nil), hosts...),
+		})
+	} else {
+		hostsCopy := append([]string(nil), lines[loc].Hosts...)
+		for _, host := range hosts {
+			if !itemInSliceString(host, hostsCopy) {
+				hostsCopy = append(hostsCopy, host)
+			}
+		}
+		lines[loc].Hosts = hostsCopy
+		lines[loc].RegenRaw()
+	}
+
+	s.replace(begin, end, lines)
+	return nil
+}
+
+func (s *Section) remove(ip string, hosts ...string) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if err := s.h.validateIP(ip); err != nil {
+		return err
+	}
+
+	begin, end, ok := s.bounds()
+	if !ok {
+		return nil
+	}
+
+	lines := make([]HostsLine, 0, end-begin-1)
+	for _, line := range s.h.Lines[begin+1 : end] {
+		if line.IP != ip || line.IsComment() {
+			lines = append(lines, line)
+			continue
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+
+		var newHosts []string
+		for _, host := range line.Hosts {
+			if !itemInSliceString(host, hosts) {
+				newHosts = append(newHosts, host)
+			}
+		}
+		if len(newHosts) == 0 {
+			continue
+		}
+		line.Hosts = newHosts
+		line.RegenRaw()
+		lines = append(lines, line)
+	}
+
+	s.replace(begin, end, lines)
+	return nil
+}
+
+func (s *Section) clear() error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if begin, end, ok := s.bounds(); ok {
+		s.replace(begin, end, nil)
+	}
+	return nil
+}
+
+// validate checks the name survives being written to a marker and read back by sectionMarker
+func (s *Section) validate() error {
+	if s.name == "" || s.name != strings.TrimSpace(s.name) || strings.ContainsAny(s.name, "\r\n"+commentChar) {
+		return fmt.Errorf("%w: %q", ErrInvalidSectionName, s.name)
+	}
+	return nil
+}
+
+// bounds returns the positions of the begin and end markers, when the end marker is missing the section runs to the end
+// of the file and end is len(Lines)
+func (s *Section) bounds() (int, int, bool) {
+	begin := -1
+	for pos, line := range s.h.Lines {
+		kind, name := sectionMarker(line)
+		if name != s.name {
+			continue
+		}
+		if kind == sectionBegin && begin < 0 {
+			begin = pos
+		}
+		if kind == sectionEnd && begin >= 0 {
+			return begin, pos, true
+		}
+	}
+	if begin < 0 {
+		return -1, -1, false
+	}
+	return begin, len(s.h.Lines), true
+}
+
+// ensure returns the bounds of the section, adding the markers to the end of the file if needed
+func (s *Section) ensure() (int, int) {
+	if begin, end, ok := s.bounds(); ok {
+		return begin, end
+	}
+	s.h.addLine(NewHostsLine(s.marker(sectionBegin)))
+	s.h.addLine(NewHostsLine(s.marker(sectionEnd)))
+	return len(s.h.Lines) - 2, len(s.h.Lines) - 1
+}
+
+// replace swaps out everything between the markers with lines and reindexes, adding the end marker if it was missing
+func (s *Section) replace(begin, end int, lines []HostsLine) {
+	newLines := make([]HostsLine, 0, len(s.h.Lines)-(end-begin-1)+len(lines)+1)
+	newLines = append(newLines, s.h.Lines[:begin+1]...)
+	newLines = append(newLines, lines...)
+	if end == len(s.h.Lines) {
+		newLines = append(newLines, NewHostsLine(s.marker(sectionEnd)))
+	} else {
+		newLines = append(newLines, s.h.Lines[end:]...)
+	}
+	s.h.Lines = newLines
+	s.h.reindex()
+}
+
+func (s *Section) marker(kind string) string {
+	return fmt.Sprintf("%s %s %s", commentChar, kind, s.name)
+}
+
+// sectionMarker returns the kind (BEGIN/END) and name of a section marker line, empty strings for any other line
+func sectionMarker(line HostsLine) (string, string) {
+	if !line.IsComment() {
+		return "", ""
+	}
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line.Raw), commentChar))
+	kind, name, found := strings.Cut(body, " ")
+	if !found || (kind != sectionBegin && kind != sectionEnd) {
+		return "", ""
+	}
+	return kind, strings.TrimSpace(name)
+}
+
+// inSection reports if the line at pos is between section markers
+func (h *Hosts) inSection(pos int) bool {
+	for i := pos - 1; i >= 0; i-- {
+		switch kind, _ := sectionMarker(h.Lines[i]); kind {
+		case sectionBegin:
+			return true
+		case sectionEnd:
+			return false
+		}
+	}
+	return false
+}
+
+// splitSections separates the lines outside of any section from the blocks of each section (markers included)
+func splitSections(lines []HostsLine) ([]HostsLine, [][]HostsLine) {
+	var outside []HostsLine
+	var blocks [][]HostsLine
+	var block []HostsLine
+	name := ""
+	for _, line := range lines {
+		kind, n := sectionMarker(line)
+		switch {
+		case block == nil && kind == sectionBegin:
+			block, name = []HostsLine{line}, n
+		case block != nil && kind == sectionEnd && n == name:
+			blocks = append(blocks, append(block, line))
+			block = nil
+		case block != nil:
+			block = append(block, line)
+		default:
+			outside = append(outside, line)
+		}
+	}
+	if block != nil {
+		blocks = append(blocks, block) // unterminated section
+	}
+	return outside, blocks
+}
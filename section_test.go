@@ -0,0 +1,117 @@
+package hostsfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSection(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost", "10.0.0.1 user")
+
+	section := hosts.Section("myapp")
+	assert.Equal(t, "myapp", section.Name())
+	assert.Nil(t, section.Lines())
+	assert.Nil(t, section.Add("10.0.0.1", "app1", "app2"))
+	assert.Nil(t, section.Add("10.0.0.2", "db"))
+	assert.Nil(t, section.Add("10.0.0.2", "app2")) // moves within the section
+	assert.Error(t, section.Add("bad", "app"))
+
+	// adds outside the section don't end up inside it
+	assert.Nil(t, hosts.Add("10.0.0.1", "user2"))
+	assert.Nil(t, hosts.Add("10.0.0.9", "db"))
+	assert.Equal(t, strings.Join([]string{
+		"127.0.0.1 localhost",
+		"10.0.0.1 user user2",
+		"# BEGIN myapp",
+		"10.0.0.1 app1",
+		"10.0.0.2 db app2",
+		"# END myapp",
+		"10.0.0.9 db",
+		"",
+	}, eol), hosts.String())
+	assert.Len(t, section.Lines(), 2)
+	assert.Equal(t, []string{"myapp"}, hosts.Sections())
+
+	assert.Nil(t, section.Remove("10.0.0.2", "app2"))
+	assert.Nil(t, section.Remove("10.0.0.1"))
+	assert.Equal(t, []HostsLine{NewHostsLine("10.0.0.2 db")}, section.Lines())
+	assert.True(t, hosts.Has("10.0.0.1", "user"))
+
+	section.Clear()
+	assert.Len(t, section.Lines(), 0)
+	assert.Equal(t, strings.Join([]string{
+		"127.0.0.1 localhost",
+		"10.0.0.1 user user2",
+		"# BEGIN myapp",
+		"# END myapp",
+		"10.0.0.9 db",
+		"",
+	}, eol), hosts.String())
+}
+
+func TestSection_Load(t *testing.T) {
+	hosts := loadHosts(t,
+		"127.0.0.1 localhost",
+		"# BEGIN other",
+		"10.0.0.5 other",
+		"# END other",
+		"#  BEGIN  myapp ",
+		"10.0.0.1 app1",
+	)
+	assert.Equal(t, []string{"other", "myapp"}, hosts.Sections())
+
+	// unterminated section gets its end marker back
+	section := hosts.Section("myapp")
+	assert.Len(t, section.Lines(), 1)
+	assert.Nil(t, section.Add("10.0.0.1", "app2"))
+	assert.Equal(t, strings.Join([]string{
+		"127.0.0.1 localhost",
+		"# BEGIN other",
+		"10.0.0.5 other",
+		"# END other",
+		"#  BEGIN  myapp ",
+		"10.0.0.1 app1 app2",
+		"# END myapp",
+		"",
+	}, eol), hosts.String())
+}
+
+func TestSection_Clean(t *testing.T) {
+	hosts := loadHosts(t,
+		"10.0.0.2 b",
+		"# BEGIN myapp",
+		"10.0.0.9 z",
+		"10.0.0.3 c",
+		"10.0.0.9 y",
+		"# END myapp",
+		"10.0.0.1 a",
+	)
+	hosts.Clean()
+	assert.Equal(t, strings.Join([]string{
+		"10.0.0.1 a",
+		"10.0.0.2 b",
+		"# BEGIN myapp",
+		"10.0.0.3 c",
+		"10.0.0.9 y z",
+		"# END myapp",
+		"",
+	}, eol), hosts.String())
+	assert.True(t, hosts.Has("10.0.0.9", "y"))
+}
+
+func TestSection_InvalidName(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost")
+	before := hosts.String()
+
+	for _, name := range []string{"", "  ", " myapp", "my\napp", "my#app"} {
+		section := hosts.Section(name)
+		assert.ErrorIs(t, section.Add("10.0.0.1", "app"), ErrInvalidSectionName, name)
+		assert.ErrorIs(t, section.Remove("10.0.0.1"), ErrInvalidSectionName, name)
+		section.Clear()
+		assert.ErrorIs(t, hosts.Err(), ErrInvalidSectionName, name)
+	}
+	assert.Equal(t, before, hosts.String())
+	assert.Empty(t, hosts.Sections())
+}
@@ -61,3 +61,15 @@ func findPositionInSliceInt(s int, slice []int) int {
 	}
 	return -1
 }
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package hostsfile
+
+// Snapshot is an immutable copy of the lines and lookups of a Hosts at a point in time. It's safe to query from any
+// number of goroutines without locking while the Hosts keeps being edited, see Hosts.Snapshot.
+type Snapshot struct {
+	lines           []HostsLine
+	ips             map[string][]int
+	hosts           map[string][]int
+	includeDisabled bool
+}
+
+// WithSnapshots publishes a Snapshot of the Hosts from the start, after that every edit, Load, Undo or Rollback builds
+// and atomically publishes a new one so readers never wait on writers, at the cost of copying all the lines on every
+// edit
+func WithSnapshots() Option {
+	return func(h *Hosts) {
+		h.config.snapshots = true
+		h.snapshot.Store(h.newSnapshot())
+	}
+}
+
+// Snapshot returns the current snapshot of the Hosts, nil unless it was made WithSnapshots. It never takes a lock so
+// it's safe to call while other goroutines edit the Hosts. Changes made to Lines directly are only seen once the next
+// snapshot is published.
+func (h *Hosts) Snapshot() *Snapshot {
+	return h.snapshot.Load()
+}
+
+// publish replaces the snapshot after the Hosts changed, callers hold the write lock
+func (h *Hosts) publish() {
+	if h.config.snapshots {
+		h.snapshot.Store(h.newSnapshot())
+	}
+}
+
+func (h *Hosts) newSnapshot() *Snapshot {
+	return &Snapshot{
+		lines:           copyLines(h.Lines),
+		ips:             h.ips.copy(),
+		hosts:           h.hosts.copy(),
+		includeDisabled: h.config.includeDisabled,
+	}
+}
+
+// Lines returns a copy of the lines in the snapshot
+func (s *Snapshot) Lines() []HostsLine {
+	return copyLines(s.lines)
+}
+
+// Has return a bool if ip/host combo in the snapshot
+func (s *Snapshot) Has(ip string, host string) bool {
+	for _, pos := range s.ips[ip] {
+		if itemInSliceInt(pos, s.hosts[host]) {
+			return true
+		}
+	}
+	return s.includeDisabled && len(disabledLines(s.lines, ip, host)) > 0
+}
+
+// HasHostname return a bool if hostname in the snapshot
+func (s *Snapshot) HasHostname(host string) bool {
+	if len(s.hosts[host]) > 0 {
+		return true
+	}
+	return s.includeDisabled && len(disabledLines(s.lines, "", host)) > 0
+}
+
+// HasIP will check if the ip exists in the snapshot
+func (s *Snapshot) HasIP(ip string) bool {
+	if len(s.ips[ip]) > 0 {
+		return true
+	}
+	return s.includeDisabled && len(disabledLines(s.lines, ip, "")) > 0
+}
+
+// HasAll returns true if every host is mapped to ip in the snapshot, with no hosts it's the same as HasIP
+func (s *Snapshot) HasAll(ip string, hosts ...string) bool {
+	if len(hosts) == 0 {
+		return s.HasIP(ip)
+	}
+	for _, host := range hosts {
+		if !s.Has(ip, host) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAny returns true if at least one of the hosts is mapped to ip in the snapshot, with no hosts it's the same as HasIP
+func (s *Snapshot) HasAny(ip string, hosts ...string) bool {
+	if len(hosts) == 0 {
+		return s.HasIP(ip)
+	}
+	for _, host := range hosts {
+		if s.Has(ip, host) {
+			return true
+		}
+	}
+	return false
+}
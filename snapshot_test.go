@@ -0,0 +1,110 @@
+package hostsfile
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_Snapshot(t *testing.T) {
+	hosts := loadHosts(t,
+		"127.0.0.1 localhost",
+		"10.0.0.1 app api",
+		"# 10.0.0.2 old",
+	)
+	assert.Nil(t, hosts.Snapshot())
+	WithSnapshots()(hosts)
+
+	snap := hosts.Snapshot()
+	assert.Same(t, snap, hosts.Snapshot())
+	assert.True(t, snap.Has("10.0.0.1", "api"))
+	assert.True(t, snap.HasHostname("localhost"))
+	assert.True(t, snap.HasIP("127.0.0.1"))
+	assert.True(t, snap.HasAll("10.0.0.1", "app", "api"))
+	assert.False(t, snap.HasAll("10.0.0.1", "app", "db"))
+	assert.True(t, snap.HasAny("10.0.0.1", "db", "app"))
+	assert.False(t, snap.HasAny("10.0.0.1", "db"))
+	assert.False(t, snap.HasIP("10.0.0.2"))
+	assert.False(t, snap.Has("10.0.0.2", "old"))
+
+	// edits publish a new snapshot and leave the old one as it was
+	assert.Nil(t, hosts.Add("10.0.0.3", "db"))
+	assert.Nil(t, hosts.Remove("10.0.0.1", "api"))
+	assert.False(t, snap.HasHostname("db"))
+	assert.True(t, snap.Has("10.0.0.1", "api"))
+	assert.Len(t, snap.Lines(), 3)
+
+	next := hosts.Snapshot()
+	assert.NotSame(t, snap, next)
+	assert.True(t, next.Has("10.0.0.3", "db"))
+	assert.False(t, next.Has("10.0.0.1", "api"))
+	assert.Equal(t, hosts.Lines, next.Lines())
+
+	// lines returned can't change the snapshot
+	lines := next.Lines()
+	lines[1].Hosts[0] = "changed"
+	assert.Equal(t, "app", next.Lines()[1].Hosts[0])
+
+	hosts.Clear()
+	assert.False(t, hosts.Snapshot().HasIP("127.0.0.1"))
+	assert.True(t, next.HasIP("127.0.0.1"))
+}
+
+func TestHosts_SnapshotPublish(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithHistory(5), WithIncludeDisabled(true),
+		WithSnapshots())
+	assert.Nil(t, err)
+	assert.True(t, hosts.Snapshot().HasHostname("localhost"))
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "app"))
+	assert.Nil(t, hosts.Disable("10.0.0.1"))
+	assert.True(t, hosts.Snapshot().Has("10.0.0.1", "app")) // WithIncludeDisabled
+
+	hosts.Undo()
+	hosts.Undo()
+	assert.False(t, hosts.Snapshot().HasIP("10.0.0.1"))
+
+	assert.NotNil(t, hosts.Transaction(func(tx *Tx) error {
+		tx.RemoveByIP("127.0.0.1")
+		assert.False(t, hosts.Snapshot().HasIP("127.0.0.1"))
+		return fmt.Errorf("rollback")
+	}))
+	assert.True(t, hosts.Snapshot().HasIP("127.0.0.1"))
+
+	assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+		_, err := io.WriteString(w, "10.0.0.9 other\n")
+		return err
+	}))
+	assert.Nil(t, hosts.Load())
+	assert.True(t, hosts.Snapshot().HasHostname("other"))
+	assert.False(t, hosts.Snapshot().HasHostname("localhost"))
+}
+
+// run with -race to check readers don't need the lock
+func TestHosts_SnapshotConcurrentReaders(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost")
+	WithConcurrencySafe()(hosts)
+	WithSnapshots()(hosts)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				snap := hosts.Snapshot()
+				assert.True(t, snap.HasHostname("localhost"))
+				snap.HasAny("10.0.0.1", "host-1", "host-2")
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		assert.Nil(t, hosts.Add("10.0.0.1", fmt.Sprintf("host-%d", j)))
+	}
+	wg.Wait()
+	assert.True(t, hosts.Snapshot().Has("10.0.0.1", "host-49"))
+}
@@ -0,0 +1,227 @@
+package hostsfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Storage is where a Hosts reads and writes its hosts file, see DiskStorage, MemoryStorage and FSStorage
+type Storage interface {
+	// Open returns a reader for the contents of the file at path
+	Open(path string) (io.ReadCloser, error)
+	// WriteFile replaces the file at path with everything written by write, readers should only ever see the old or
+	// the new contents
+	WriteFile(path string, write func(io.Writer) error) error
+	// Stat returns the file info of the file at path
+	Stat(path string) (fs.FileInfo, error)
+	// Backup copies the file at path to dest
+	Backup(path, dest string) error
+}
+
+// writableStorage is implemented by storages that can tell if a file can be written without trying to write it
+type writableStorage interface {
+	IsWritable(path string) bool
+}
+
+// lockingStorage is implemented by storages that can lock a file against other processes, see Hosts.Lock. Storages
+// that don't are only locked against other Hosts in the same process.
+type lockingStorage interface {
+	Lock(path string) (unlock func() error, err error)
+}
+
+// ErrReadOnly is returned when writing to a read only Storage
+var ErrReadOnly = errors.New("storage is read only")
+
+// ErrBackupUnsupported is returned when a BackupManager is used with a hosts file that isn't in DiskStorage, backups
+// are timestamped files in a directory on disk
+var ErrBackupUnsupported = errors.New("backup manager only supports hosts files in DiskStorage")
+
+// isDiskStorage returns true if storage reads and writes the local filesystem
+func isDiskStorage(storage Storage) bool {
+	switch storage.(type) {
+	case DiskStorage, *DiskStorage:
+		return true
+	}
+	return false
+}
+
+// DiskStorage reads and writes hosts files on the local filesystem, it's used when no other storage is set
+type DiskStorage struct{}
+
+func (DiskStorage) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// WriteFile writes to a temp file and renames it over path, see Hosts.Flush
+func (DiskStorage) WriteFile(path string, write func(io.Writer) error) error {
+	return writeFileAtomic(path, write)
+}
+
+func (DiskStorage) Stat(path string) (fs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (DiskStorage) Backup(path, dest string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, source)
+	return err
+}
+
+// Lock takes an flock on path, or creates it exclusively where flock isn't available
+func (DiskStorage) Lock(path string) (func() error, error) {
+	l, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.unlock, nil
+}
+
+func (DiskStorage) IsWritable(path string) bool {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0660)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	return true
+}
+
+// MemoryStorage keeps hosts files in memory, useful for tests and sandboxes. It's safe for concurrent use.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+	locks processLocks
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStorage returns a MemoryStorage holding a copy of files, keyed by path
+func NewMemoryStorage(files map[string][]byte) *MemoryStorage {
+	m := &MemoryStorage{files: make(map[string]memoryFile)}
+	for path, data := range files {
+		m.files[path] = memoryFile{data: append([]byte(nil), data...), modTime: time.Now()}
+	}
+	return m
+}
+
+func (m *MemoryStorage) Open(path string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// WriteFile collects everything written and only replaces the file once write succeeds
+func (m *MemoryStorage) WriteFile(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return &fs.PathError{Op: "write", Path: path, Err: fs.ErrInvalid}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := write(buf); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string]memoryFile)
+	}
+	m.files[path] = memoryFile{data: buf.Bytes(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryStorage) Stat(path string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+	return memoryFileInfo{name: path, file: f}, nil
+}
+
+func (m *MemoryStorage) Backup(path, dest string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[path]
+	if !ok {
+		return &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	m.files[dest] = memoryFile{data: f.data, modTime: time.Now()}
+	return nil
+}
+
+// Lock locks path against every Hosts using the MemoryStorage
+func (m *MemoryStorage) Lock(path string) (func() error, error) {
+	return m.locks.lock(path), nil
+}
+
+func (m *MemoryStorage) IsWritable(path string) bool {
+	return path != ""
+}
+
+// memoryFileInfo is the fs.FileInfo for a file in a MemoryStorage
+type memoryFileInfo struct {
+	name string
+	file memoryFile
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return int64(len(i.file.data)) }
+func (i memoryFileInfo) Mode() fs.FileMode  { return defaultFileMode }
+func (i memoryFileInfo) ModTime() time.Time { return i.file.modTime }
+func (i memoryFileInfo) IsDir() bool        { return false }
+func (i memoryFileInfo) Sys() any           { return nil }
+
+// FSStorage is a read only Storage reading hosts files from an fs.FS such as an embed.FS or an image layer, paths
+// follow the fs.FS rules so they are slash separated and unrooted e.g. "etc/hosts"
+type FSStorage struct {
+	FS fs.FS
+}
+
+// NewFSStorage returns a read only Storage for fsys
+func NewFSStorage(fsys fs.FS) *FSStorage {
+	return &FSStorage{FS: fsys}
+}
+
+func (s *FSStorage) Open(path string) (io.ReadCloser, error) {
+	return s.FS.Open(path)
+}
+
+func (s *FSStorage) WriteFile(path string, write func(io.Writer) error) error {
+	return &fs.PathError{Op: "write", Path: path, Err: ErrReadOnly}
+}
+
+func (s *FSStorage) Stat(path string) (fs.FileInfo, error) {
+	return fs.Stat(s.FS, path)
+}
+
+func (s *FSStorage) Backup(path, dest string) error {
+	return &fs.PathError{Op: "write", Path: dest, Err: ErrReadOnly}
+}
+
+func (s *FSStorage) IsWritable(path string) bool {
+	return false
+}
@@ -0,0 +1,68 @@
+package hostsfile
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readStorage(t *testing.T, storage Storage, path string) string {
+	f, err := storage.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{
+		"/etc/hosts": []byte("127.0.0.1 localhost\n"),
+	})
+
+	hosts, err := NewHostsWithStorage("/etc/hosts", storage)
+	assert.Nil(t, err)
+	assert.Equal(t, storage, hosts.Storage())
+	assert.True(t, hosts.IsWritable())
+	assert.True(t, hosts.Has("127.0.0.1", "localhost"))
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "host1"))
+	assert.Nil(t, hosts.Flush())
+	assert.Equal(t, "127.0.0.1 localhost"+eol+"10.0.0.1 host1"+eol, readStorage(t, storage, "/etc/hosts"))
+
+	assert.Nil(t, hosts.Backup())
+	assert.Equal(t, readStorage(t, storage, "/etc/hosts"), readStorage(t, storage, "/etc/hosts.bak"))
+
+	// concurrent modification detection works the same as on disk
+	other, err := NewHostsWithStorage("/etc/hosts", storage)
+	assert.Nil(t, err)
+	assert.Nil(t, other.Add("10.0.0.2", "host2"))
+	assert.Nil(t, other.Flush())
+
+	modified, err := hosts.HasBeenModified()
+	assert.Nil(t, err)
+	assert.True(t, modified)
+	assert.Nil(t, hosts.Add("10.0.0.3", "host3"))
+	assert.ErrorIs(t, hosts.Flush(), ErrConcurrentModification)
+
+	_, err = NewHostsWithStorage("/noexist", storage)
+	assert.Error(t, err)
+}
+
+func TestFSStorage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/hosts": &fstest.MapFile{Data: []byte("127.0.0.1 localhost\n")},
+	}
+
+	hosts, err := NewHostsWithStorage("etc/hosts", NewFSStorage(fsys))
+	assert.Nil(t, err)
+	assert.True(t, hosts.Has("127.0.0.1", "localhost"))
+	assert.False(t, hosts.IsWritable())
+
+	assert.Nil(t, hosts.Add("10.0.0.1", "host1"))
+	assert.ErrorIs(t, hosts.Flush(), ErrReadOnly)
+	assert.ErrorIs(t, hosts.Backup(), ErrReadOnly)
+	assert.Equal(t, "127.0.0.1 localhost\n", string(fsys["etc/hosts"].Data))
+}
@@ -0,0 +1,158 @@
+package hostsfile
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTxDone is returned when committing or rolling back a transaction that was already committed or rolled back
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Tx is an in-memory transaction on a Hosts started with Begin. Edits are made through the embedded Hosts as usual and
+// Rollback puts Lines and the lookups back to how they were at Begin. Only memory is covered, a Flush during the
+// transaction isn't undone. A Load during the transaction is rolled back too so the next Flush still sees the file as
+// changed since Begin. Transactions can be nested, each one rolls back to its own Begin.
+//
+// Transactions aren't isolated. With WithConcurrencySafe other goroutines can still edit the Hosts between Begin and
+// Rollback, the transaction sees their edits and Rollback discards them along with its own even though they already
+// succeeded. Keep other writers out for the length of a transaction, e.g. with a lock of your own.
+type Tx struct {
+	*Hosts
+	snapshot hostsState
+	done     bool
+}
+
+// hostsState is a copy of everything the edits change
+type hostsState struct {
+	lines   []HostsLine
+	ips     map[string][]int
+	hosts   map[string][]int
+	pending []edit
+	history *history   // only kept for transactions, the history has its own states
+	file    *fileState // only kept for transactions and Watch, undo keeps comparing against the latest file
+}
+
+// fileState is what Load knows about the hosts file, Flush compares it with the file to detect changes made by others
+type fileState struct {
+	modTime    time.Time
+	checksum   []byte
+	base       []HostsLine
+	missingEOL bool
+	generation int
+}
+
+// Begin starts a transaction
+func (h *Hosts) Begin() *Tx {
+	defer h.lockRead()()
+	snapshot := h.state()
+	history := h.history.copy()
+	snapshot.history = &history
+	file := h.fileState()
+	snapshot.file = &file
+	return &Tx{Hosts: h, snapshot: snapshot}
+}
+
+// Commit keeps the changes made in the transaction
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	return nil
+}
+
+// Rollback discards the changes made in the transaction
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	defer tx.Hosts.lockWrite()()
+	tx.Hosts.restoreWithEvents("rollback", tx.snapshot)
+	return nil
+}
+
+// Transaction runs fn in a transaction, it's committed when fn returns nil and rolled back when fn returns an error or
+// panics
+func (h *Hosts) Transaction(fn func(tx *Tx) error) (err error) {
+	tx := h.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil && !errors.Is(commitErr, ErrTxDone) {
+			err = commitErr
+		}
+	}()
+	return fn(tx)
+}
+
+func (h *Hosts) state() hostsState {
+	return hostsState{
+		lines:   copyLines(h.Lines),
+		ips:     h.ips.copy(),
+		hosts:   h.hosts.copy(),
+		pending: append([]edit(nil), h.pending...),
+	}
+}
+
+// restore puts back a state taken by state, it's copied again as states can be shared by transactions and the history
+func (h *Hosts) restore(s hostsState) {
+	h.Lines = copyLines(s.lines)
+	h.ips.replace(copyPositions(s.ips))
+	h.hosts.replace(copyPositions(s.hosts))
+	h.pending = append([]edit(nil), s.pending...)
+	if s.history != nil {
+		h.history = *s.history
+	}
+	if s.file != nil {
+		h.modTime, h.checksum, h.base = s.file.modTime, s.file.checksum, s.file.base
+		h.missingEOL, h.generation = s.file.missingEOL, s.file.generation
+	}
+	h.publish()
+}
+
+func (h *Hosts) fileState() fileState {
+	return fileState{
+		modTime:    h.modTime,
+		checksum:   h.checksum,
+		base:       h.base,
+		missingEOL: h.missingEOL,
+		generation: h.generation,
+	}
+}
+
+func copyPositions(l map[string][]int) map[string][]int {
+	out := make(map[string][]int, len(l))
+	for key, positions := range l {
+		out[key] = append([]int(nil), positions...)
+	}
+	return out
+}
+
+// copyLines deep copies lines, some edits change the hosts of a line in place
+func copyLines(lines []HostsLine) []HostsLine {
+	if lines == nil {
+		return nil
+	}
+	out := make([]HostsLine, len(lines))
+	for i, line := range lines {
+		if line.Hosts != nil {
+			line.Hosts = append(make([]string, 0, len(line.Hosts)), line.Hosts...)
+		}
+		if line.Metadata != nil {
+			metadata := make(map[string]string, len(line.Metadata))
+			for key, value := range line.Metadata {
+				metadata[key] = value
+			}
+			line.Metadata = metadata
+		}
+		out[i] = line
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package hostsfile
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_Transaction(t *testing.T) {
+	hosts := loadHosts(t,
+		"127.0.0.1 localhost",
+		"10.0.0.1 c b a",
+	)
+	before := hosts.String()
+	beforeLines := copyLines(hosts.Lines)
+
+	err := hosts.Transaction(func(tx *Tx) error {
+		tx.RemoveByIP("10.0.0.1")
+		assert.Nil(t, tx.Add("10.0.0.2", "app"))
+		tx.Clean()
+		return tx.Add("10.0.0.3", "bad_host!")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, before, hosts.String())
+	assert.Equal(t, beforeLines, hosts.Lines)
+	assert.True(t, hosts.Has("10.0.0.1", "b"))
+	assert.False(t, hosts.HasIP("10.0.0.2"))
+	assert.Equal(t, []int{1}, hosts.hosts.get("a"))
+	assert.Empty(t, hosts.pending)
+
+	assert.Nil(t, hosts.Transaction(func(tx *Tx) error {
+		return tx.Add("10.0.0.2", "app")
+	}))
+	assert.True(t, hosts.Has("10.0.0.2", "app"))
+	assert.Len(t, hosts.pending, 1)
+}
+
+func TestHosts_TransactionSortsInPlace(t *testing.T) {
+	hosts := loadHosts(t, "10.0.0.1 c b a")
+	tx := hosts.Begin()
+	tx.SortHosts()
+	assert.Equal(t, []string{"a", "b", "c"}, hosts.Lines[0].Hosts)
+	assert.Nil(t, tx.Rollback())
+	assert.Equal(t, []string{"c", "b", "a"}, hosts.Lines[0].Hosts)
+	assert.Equal(t, "10.0.0.1 c b a", hosts.Lines[0].ToRaw())
+
+	assert.ErrorIs(t, tx.Rollback(), ErrTxDone)
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+}
+
+func TestHosts_TransactionSections(t *testing.T) {
+	hosts := loadHosts(t,
+		"127.0.0.1 localhost",
+		"# BEGIN app",
+		"10.0.0.1 app",
+		"# END app",
+	)
+	before := hosts.String()
+
+	outer := hosts.Begin()
+	assert.Nil(t, outer.Section("app").Add("10.0.0.2", "db"))
+
+	err := hosts.Transaction(func(tx *Tx) error {
+		tx.Section("app").Clear()
+		assert.Nil(t, tx.Section("other").Add("10.0.0.3", "cache"))
+		return errors.New("nope")
+	})
+	assert.EqualError(t, err, "nope")
+	assert.True(t, hosts.Has("10.0.0.2", "db"))
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.Equal(t, []string{"app"}, hosts.Sections())
+
+	assert.Nil(t, outer.Rollback())
+	assert.Equal(t, before, hosts.String())
+	assert.False(t, hosts.HasIP("10.0.0.2"))
+
+	assert.Panics(t, func() {
+		_ = hosts.Transaction(func(tx *Tx) error {
+			tx.Clear()
+			panic("boom")
+		})
+	})
+	assert.Equal(t, before, hosts.String())
+}
+
+func TestHosts_TransactionRollbackLoad(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"))
+	assert.Nil(t, err)
+
+	tx := hosts.Begin()
+	assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 localhost\n10.0.0.1 external\n")
+		return err
+	}))
+	assert.Nil(t, tx.Load())
+	assert.True(t, hosts.HasHostname("external"))
+	assert.Nil(t, tx.Rollback())
+	assert.False(t, hosts.HasHostname("external"))
+
+	// the external edit is still seen as a conflict instead of being overwritten
+	modified, err := hosts.HasBeenModified()
+	assert.Nil(t, err)
+	assert.True(t, modified)
+	assert.ErrorIs(t, hosts.Flush(), ErrConcurrentModification)
+	assert.Contains(t, readStorage(t, storage, "hosts"), "external")
+}
@@ -0,0 +1,105 @@
+package hostsfile
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// defaultWatchInterval is how often Watch polls the hosts file when WithWatchInterval isn't set
+const defaultWatchInterval = time.Second
+
+// ErrNotConcurrencySafe is returned by Watch when the Hosts wasn't made WithConcurrencySafe
+var ErrNotConcurrencySafe = errors.New("hosts has to be made WithConcurrencySafe to be watched")
+
+// WatchEvent is sent by Watch after the hosts file was changed by someone else and reloaded
+type WatchEvent struct {
+	Diff *HostsDiff // what changed going from the lines before the reload to after it
+	Err  error      // set when the changed file couldn't be reloaded, Lines are left as they were
+}
+
+// WithWatchInterval sets how often Watch polls the hosts file for changes
+func WithWatchInterval(interval time.Duration) Option {
+	return func(h *Hosts) {
+		h.config.watchInterval = interval
+	}
+}
+
+// Watch reloads the hosts file whenever it changes and sends what changed on the returned channel until ctx is done.
+// On Linux changes to files on disk are picked up straight away with inotify, everywhere else and for other storages
+// the file is polled comparing the modification time and then the contents. Edits made since Load are replayed on top
+// of the reloaded file the same as ConflictReapply. Watch changes the Hosts from its own goroutine so the Hosts has to
+// be made WithConcurrencySafe, use the methods or a Snapshot to read it while watching and never Lines directly.
+func (h *Hosts) Watch(ctx context.Context) (<-chan WatchEvent, error) {
+	if h.mu == nil {
+		return nil, ErrNotConcurrencySafe
+	}
+
+	var notify <-chan struct{}
+	if isDiskStorage(h.Storage()) {
+		var err error
+		if notify, err = notifyChanges(ctx, h.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	interval := h.config.watchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-notify:
+			case <-ticker.C:
+			}
+
+			event, ok := h.reloadChanged()
+			if !ok {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// reloadChanged reloads the hosts file if it changed, false when there's nothing to report
+func (h *Hosts) reloadChanged() (WatchEvent, bool) {
+	defer h.lockWrite()()
+	changed, err := h.hasBeenModified()
+	if errors.Is(err, fs.ErrNotExist) {
+		return WatchEvent{}, false // in the middle of being replaced, the new file shows up in a later check
+	}
+	if err != nil {
+		return WatchEvent{Err: err}, true
+	}
+	if !changed {
+		return WatchEvent{}, false
+	}
+
+	before := h.state()
+	file := h.fileState()
+	before.file = &file
+	if err := h.reapply(); err != nil {
+		// keep comparing against the old file so the change is still detected as a conflict on Flush
+		h.restore(before)
+		return WatchEvent{Err: err}, true
+	}
+
+	d := diff(h.Path, h.Path, before.lines, h.Lines)
+	return WatchEvent{Diff: d}, d.HasChanges()
+}
@@ -0,0 +1,65 @@
+package hostsfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// notifyChanges uses inotify to signal changes to the file at path. The directory is watched rather than the file so
+// editors and atomic writes that rename a new file over it keep getting picked up.
+func notifyChanges(ctx context.Context, path string) (<-chan struct{}, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	// only once the file is complete, a write in place truncates first so reading on IN_MODIFY can see it empty
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	// a non blocking fd goes through the runtime poller so closing the file stops the pending read
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + syscall.SizeofInotifyEvent
+				offset = start + int(event.Len)
+				if strings.TrimRight(string(buf[start:offset]), "\x00") != name {
+					continue
+				}
+				select {
+				case changes <- struct{}{}:
+				default: // a check is already due
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package hostsfile
+
+import "context"
+
+// notifyChanges isn't supported here, Watch falls back to polling
+func notifyChanges(ctx context.Context, path string) (<-chan struct{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+package hostsfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nextWatchEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	select {
+	case event, ok := <-events:
+		assert.True(t, ok)
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no watch event")
+	}
+	return WatchEvent{}
+}
+
+func TestHosts_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	assert.Nil(t, os.WriteFile(path, []byte("127.0.0.1 localhost\n"), 0644))
+	hosts, err := New(WithPath(path), WithWatchInterval(time.Minute), WithConcurrencySafe()) // on linux the changes have to come from inotify
+	assert.Nil(t, err)
+	assert.Nil(t, hosts.Add("10.0.0.9", "local"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := hosts.Watch(ctx)
+	assert.Nil(t, err)
+
+	// written in place
+	assert.Nil(t, os.WriteFile(path, []byte("127.0.0.1 localhost\n10.0.0.1 app\n"), 0644))
+	event := nextWatchEvent(t, events)
+	assert.Nil(t, event.Err)
+	assert.Equal(t, []Change{{Kind: ChangeAdded, IP: "10.0.0.1", Hosts: []string{"app"}, Line: 2}}, event.Diff.Changes)
+	assert.True(t, hosts.Has("10.0.0.1", "app"))
+	assert.True(t, hosts.Has("10.0.0.9", "local"), "local edits are replayed")
+
+	// replaced by renaming a new file over it like most editors do
+	assert.Nil(t, writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 localhost\n10.0.0.2 app\n")
+		return err
+	}))
+	event = nextWatchEvent(t, events)
+	assert.Nil(t, event.Err)
+	assert.Equal(t, []Change{{Kind: ChangeIPChanged, IP: "10.0.0.2", OldIP: "10.0.0.1", Hosts: []string{"app"}, Line: 2, OldLine: 2}}, event.Diff.Changes)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestHosts_WatchPolling(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithWatchInterval(5*time.Millisecond), WithConcurrencySafe())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := hosts.Watch(ctx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 localhost\n10.0.0.1 app\n")
+		return err
+	}))
+	event := nextWatchEvent(t, events)
+	assert.Nil(t, event.Err)
+	assert.Len(t, event.Diff.Changes, 1)
+
+	// and removed again
+	assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+		_, err := io.WriteString(w, "127.0.0.1 localhost\n")
+		return err
+	}))
+	assert.Nil(t, nextWatchEvent(t, events).Err)
+	assert.False(t, hosts.HasIP("10.0.0.1"))
+}
+
+// run with -race to check the watcher and the methods don't race
+func TestHosts_WatchLocks(t *testing.T) {
+	storage := NewMemoryStorage(map[string][]byte{"hosts": []byte("127.0.0.1 localhost\n")})
+	hosts, err := New(WithStorage(storage), WithPath("hosts"), WithWatchInterval(time.Millisecond), WithConcurrencySafe(),
+		WithSnapshots())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := hosts.Watch(ctx)
+	assert.Nil(t, err)
+
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, storage.WriteFile("hosts", func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "127.0.0.1 localhost\n10.0.0.%d app\n", i)
+			return err
+		}))
+		assert.Nil(t, nextWatchEvent(t, events).Err)
+		assert.True(t, hosts.HasHostname("app"))
+		assert.True(t, hosts.Snapshot().HasHostname("localhost"))
+		_ = hosts.String()
+	}
+}
+
+func TestHosts_WatchNeedsConcurrencySafe(t *testing.T) {
+	hosts := loadHosts(t, "127.0.0.1 localhost")
+	events, err := hosts.Watch(context.Background())
+	assert.ErrorIs(t, err, ErrNotConcurrencySafe)
+	assert.Nil(t, events)
+	assert.Nil(t, hosts.mu, "Watch doesn't turn it on by itself")
+}